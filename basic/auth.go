@@ -0,0 +1,8 @@
+package basic
+
+type AuthConfig struct {
+	// 服务端接受的token，每项为"identity=token"
+	Tokens []string
+	// 允许访问的调用方身份，为空时不限制
+	Allow []string
+}
@@ -2,17 +2,17 @@ package basic
 
 import (
 	"context"
+	"errors"
 	"github.com/coreos/etcd/clientv3"
+	clientNamespace "github.com/coreos/etcd/clientv3/namespace"
 	"github.com/coreos/etcd/mvcc/mvccpb"
+	"go.uber.org/zap"
+	"strings"
 	"time"
 )
 
-// 定义key变更事件常量
-const (
-	KeyCreateChangeEvent = iota
-	KeyUpdateChangeEvent
-	KeyDeleteChangeEvent
-)
+// 默认的key前缀
+const DefaultEtcdNamespace = "msnowflake/"
 
 var (
 	etcd *Etcd
@@ -22,25 +22,27 @@ type EtcdConfig struct {
 	Endpoints      []string
 	ConnectTimeout time.Duration
 	ReadTimeout    time.Duration
+	// 连接etcd的客户端证书和CA，都为空时不使用TLS
+	TLS TLSConfig
+	// etcd开启RBAC时的用户名和密码
+	Username string
+	Password string
+	// etcd暂时不可用或单次请求超时时的最大重试次数，为0时不重试
+	MaxRetries int
+	// 第一次重试前等待的时间，之后每次翻倍，不超过MaxRetryBackoff
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+	// 所有key的前缀，共用一个etcd的多套部署(如测试和生产)使用不同的前缀，为空时使用DefaultEtcdNamespace
+	Namespace string
 }
 
 type Etcd struct {
 	endpoints []string
+	namespace string
 	client    *clientv3.Client
 	kv        clientv3.KV
-	timeout   time.Duration
-}
-
-type KeyChangeEvent struct {
-	Type  int
-	Key   string
-	Value []byte
-}
-
-type WatchKeyChangeResponse struct {
-	Event      chan *KeyChangeEvent
-	CancelFunc context.CancelFunc
-	Watcher    clientv3.Watcher
+	timeout   time.Duration // 单次请求的超时时间，为0时只受调用方ctx限制
+	retry     retryPolicy
 }
 
 type TxResponse struct {
@@ -49,50 +51,128 @@ type TxResponse struct {
 	Lease   clientv3.Lease
 	Key     string
 	Value   string
+	// TxKeepaliveWithTTL成功时有效，续约停止(租约过期、被撤销或连接关闭)后关闭
+	Done <-chan struct{}
 }
 
-func InitEtcd(config EtcdConfig) error {
+func InitEtcd(config EtcdConfig) (err error) {
 	if etcd != nil {
 		return nil
 	}
+	etcd, err = NewEtcd(config)
+	return err
+}
 
-	client, err := clientv3.New(clientv3.Config{
-		Endpoints:   config.Endpoints,
-		DialTimeout: config.ConnectTimeout,
-	})
+// 创建一个不影响全局etcd的连接，用于嵌入到其他服务中使用
+func NewEtcd(config EtcdConfig) (*Etcd, error) {
+	clientConfig, err := config.clientConfig()
+	if err != nil {
+		return nil, err
+	}
+	client, err := clientv3.New(clientConfig)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
+	// 替换client的KV、Watcher和Lease，分布式锁和选举直接使用client，同样在命名空间内
+	namespace := config.GetNamespace()
+	client.KV = clientNamespace.NewKV(client.KV, namespace)
+	client.Watcher = clientNamespace.NewWatcher(client.Watcher, namespace)
+	client.Lease = clientNamespace.NewLease(client.Lease, namespace)
 
-	etcd = &Etcd{
+	return &Etcd{
 		endpoints: config.Endpoints,
+		namespace: namespace,
 		client:    client,
-		kv:        clientv3.NewKV(client),
+		kv:        client.KV,
 		timeout:   config.ReadTimeout,
+		retry: retryPolicy{
+			maxRetries: config.MaxRetries,
+			backoff:    config.RetryBackoff,
+			maxBackoff: maxDuration(config.MaxRetryBackoff, config.RetryBackoff),
+		},
+	}, nil
+}
+
+func (config EtcdConfig) clientConfig() (clientv3.Config, error) {
+	clientConfig := clientv3.Config{
+		Endpoints:   config.Endpoints,
+		DialTimeout: config.ConnectTimeout,
+		Username:    config.Username,
+		Password:    config.Password,
+	}
+	if len(config.Username) == 0 && len(config.Password) > 0 {
+		return clientConfig, errors.New("配置了etcd密码时必须配置用户名")
+	}
+	if config.TLS.Enabled() {
+		tlsConfig, err := config.TLS.ClientTLS()
+		if err != nil {
+			zap.S().Errorw("etcd的TLS配置不正确", "err", err)
+			return clientConfig, err
+		}
+		clientConfig.TLS = tlsConfig
 	}
+	return clientConfig, nil
+}
 
-	return nil
+func (config EtcdConfig) GetNamespace() string {
+	namespace := config.Namespace
+	if len(namespace) == 0 {
+		namespace = DefaultEtcdNamespace
+	}
+	if !strings.HasSuffix(namespace, "/") {
+		namespace += "/"
+	}
+	return namespace
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
 }
 
 func GetEtcd() *Etcd {
 	return etcd
 }
 
-// 根据key获取value
+// 所有key的前缀，方法中的key都是相对于该前缀的
+func (etcd *Etcd) Namespace() string {
+	return etcd.namespace
+}
+
+func (etcd *Etcd) newWatcher() clientv3.Watcher {
+	return clientNamespace.NewWatcher(clientv3.NewWatcher(etcd.client), etcd.namespace)
+}
+
+// 根据key获取value，key不存在时value为nil
 func (etcd *Etcd) Get(key string) (value []byte, err error) {
-	var (
-		getResponse *clientv3.GetResponse
-	)
-	ctx, cancelFunc := context.WithTimeout(context.Background(), etcd.timeout)
-	defer cancelFunc()
-	if getResponse, err = etcd.kv.Get(ctx, key); err != nil {
-		return
+	if value, err = etcd.GetWithContext(context.Background(), key); IsNotFound(err) {
+		return nil, nil
+	}
+	return
+}
+
+// 根据key获取value，key不存在时返回NotFound错误
+func (etcd *Etcd) GetWithContext(ctx context.Context, key string) ([]byte, error) {
+	getResponse, err := etcd.get(ctx, "Get", key)
+	if err != nil {
+		return nil, err
 	}
 	if len(getResponse.Kvs) == 0 {
-		return
+		return nil, &EtcdError{Code: EtcdErrorNotFound, Op: "Get", Key: key}
 	}
-	value = getResponse.Kvs[0].Value
+	return getResponse.Kvs[0].Value, nil
+}
+
+func (etcd *Etcd) get(ctx context.Context, op, key string, opts ...clientv3.OpOption) (getResponse *clientv3.GetResponse, err error) {
+	ctx, span := startEtcdSpan(ctx, op, key)
+	defer func() { EndSpan(span, err) }()
+	err = etcd.do(ctx, op, key, func(ctx context.Context) (err error) {
+		getResponse, err = etcd.kv.Get(ctx, key, opts...)
+		return
+	})
 	return
 }
 
@@ -101,10 +181,7 @@ func (etcd *Etcd) GetWithPrefixKey(prefixKey string) (keys [][]byte, values [][]
 	var (
 		getResponse *clientv3.GetResponse
 	)
-	ctx, cancelFunc := context.WithTimeout(context.Background(), etcd.timeout)
-	defer cancelFunc()
-
-	if getResponse, err = etcd.kv.Get(ctx, prefixKey, clientv3.WithPrefix()); err != nil {
+	if getResponse, err = etcd.get(context.Background(), "GetWithPrefixKey", prefixKey, clientv3.WithPrefix()); err != nil {
 		return
 	}
 
@@ -126,10 +203,7 @@ func (etcd *Etcd) GetWithPrefixKeyLimit(prefixKey string, limit int64) (keys [][
 	var (
 		getResponse *clientv3.GetResponse
 	)
-	ctx, cancelFunc := context.WithTimeout(context.Background(), etcd.timeout)
-	defer cancelFunc()
-
-	if getResponse, err = etcd.kv.Get(ctx, prefixKey, clientv3.WithPrefix(), clientv3.WithLimit(limit)); err != nil {
+	if getResponse, err = etcd.get(context.Background(), "GetWithPrefixKeyLimit", prefixKey, clientv3.WithPrefix(), clientv3.WithLimit(limit)); err != nil {
 		return
 	}
 
@@ -146,15 +220,33 @@ func (etcd *Etcd) GetWithPrefixKeyLimit(prefixKey string, limit int64) (keys [][
 	return
 }
 
+// 根据key前缀获取key的完整信息(包括租约和revision)，同时返回读取时的revision，可以从revision+1开始watch
+func (etcd *Etcd) ListWithContext(ctx context.Context, prefixKey string) (kvs []*mvccpb.KeyValue, revision int64, err error) {
+	getResponse, err := etcd.get(ctx, "List", prefixKey, clientv3.WithPrefix())
+	if err != nil {
+		return nil, 0, err
+	}
+	return getResponse.Kvs, getResponse.Header.Revision, nil
+}
+
 // put一个值
 func (etcd *Etcd) Put(key, value string) (err error) {
-	ctx, cancelFunc := context.WithTimeout(context.Background(), etcd.timeout)
-	defer cancelFunc()
+	return etcd.PutWithContext(context.Background(), key, value)
+}
 
-	if _, err = etcd.client.Put(ctx, key, value); err != nil {
-		return
-	}
-	return
+// put一个绑定租约的值，租约失效后key被删除
+func (etcd *Etcd) PutWithLease(key, value string, leaseID clientv3.LeaseID) (err error) {
+	return etcd.PutWithContext(context.Background(), key, value, clientv3.WithLease(leaseID))
+}
+
+// put一个值，opts可以指定租约等
+func (etcd *Etcd) PutWithContext(ctx context.Context, key, value string, opts ...clientv3.OpOption) (err error) {
+	ctx, span := startEtcdSpan(ctx, "Put", key)
+	defer func() { EndSpan(span, err) }()
+	return etcd.do(ctx, "Put", key, func(ctx context.Context) error {
+		_, err := etcd.kv.Put(ctx, key, value, opts...)
+		return err
+	})
 }
 
 // Put一个不存在的值
@@ -162,138 +254,119 @@ func (etcd *Etcd) PutNotExist(key, value string) (success bool, oldValue []byte,
 	var (
 		txnResponse *clientv3.TxnResponse
 	)
-	ctx, cancelFunc := context.WithTimeout(context.Background(), etcd.timeout)
-	defer cancelFunc()
-
-	txn := etcd.client.Txn(ctx)
-	txnResponse, err = txn.If(clientv3.Compare(clientv3.Version(key), "=", 0)).
-		Then(clientv3.OpPut(key, value)).
-		Else(clientv3.OpGet(key)).
-		Commit()
-
+	txnResponse, err = etcd.TxnWithContext(context.Background(),
+		[]clientv3.Cmp{clientv3.Compare(clientv3.Version(key), "=", 0)},
+		[]clientv3.Op{clientv3.OpPut(key, value)},
+		clientv3.OpGet(key))
+
+	if IsConflict(err) {
+		// 比较和读取之间key可能已经被删除
+		if kvs := txnResponse.Responses[0].GetResponseRange().Kvs; len(kvs) > 0 {
+			oldValue = kvs[0].Value
+		}
+		return false, oldValue, nil
+	}
 	if err != nil {
 		return
 	}
-
-	if txnResponse.Succeeded {
-		success = true
-	} else {
-		oldValue = make([]byte, 0)
-		oldValue = txnResponse.Responses[0].GetResponseRange().Kvs[0].Value
-	}
+	success = true
 	return
 }
 
 // 更新一个已经存在的值
 func (etcd *Etcd) Update(key, value, oldValue string) (success bool, err error) {
-	var (
-		txnResponse *clientv3.TxnResponse
-	)
-	ctx, cancelFunc := context.WithTimeout(context.Background(), etcd.timeout)
-	defer cancelFunc()
+	_, err = etcd.TxnWithContext(context.Background(),
+		[]clientv3.Cmp{clientv3.Compare(clientv3.Value(key), "=", oldValue)},
+		[]clientv3.Op{clientv3.OpPut(key, value)})
 
-	txn := etcd.client.Txn(ctx)
-	txnResponse, err = txn.If(clientv3.Compare(clientv3.Value(key), "=", oldValue)).
-		Then(clientv3.OpPut(key, value)).
-		Commit()
+	if IsConflict(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
 
-	if err != nil {
+// 执行事务，比较条件不满足时返回Conflict错误，同时返回执行elseOps的结果。
+// 超时的事务可能已经执行成功，重试会因为条件不再满足而把自己的写入当成冲突，所以只在确定没有执行时重试，
+// 超时直接返回Timeout错误
+func (etcd *Etcd) TxnWithContext(ctx context.Context, cmps []clientv3.Cmp, thenOps []clientv3.Op, elseOps ...clientv3.Op) (txnResponse *clientv3.TxnResponse, err error) {
+	var key string
+	if len(cmps) > 0 {
+		key = string(cmps[0].Key)
+	}
+	ctx, span := startEtcdSpan(ctx, "Txn", key)
+	defer func() { EndSpan(span, err) }()
+	err = etcd.doRetryIf(ctx, "Txn", key, isUnapplied, func(ctx context.Context) (err error) {
+		txnResponse, err = etcd.kv.Txn(ctx).If(cmps...).Then(thenOps...).Else(elseOps...).Commit()
 		return
+	})
+	if err == nil && !txnResponse.Succeeded {
+		err = &EtcdError{Code: EtcdErrorConflict, Op: "Txn", Key: key}
 	}
-
-	return txnResponse.Succeeded, err
+	return
 }
 
 // 根据key删除
 func (etcd *Etcd) Delete(key string) (err error) {
-	ctx, cancelFunc := context.WithTimeout(context.Background(), etcd.timeout)
-	defer cancelFunc()
-
-	_, err = etcd.kv.Delete(ctx, key)
+	_, err = etcd.DeleteWithContext(context.Background(), key)
 	return
 }
 
 // 根据一个key前缀删除
 func (etcd *Etcd) DeleteWithPrefixKey(prefixKey string) (err error) {
-	ctx, cancelFunc := context.WithTimeout(context.Background(), etcd.timeout)
-	defer cancelFunc()
-	_, err = etcd.client.Delete(ctx, prefixKey, clientv3.WithPrefix())
+	_, err = etcd.DeleteWithContext(context.Background(), prefixKey, clientv3.WithPrefix())
 	return
 }
 
-// watch 一个key
-func (etcd *Etcd) Watch(key string) (keyChangeEventResponse *WatchKeyChangeResponse) {
-	watcher := clientv3.NewWatcher(etcd.client)
-	watchChans := watcher.Watch(context.Background(), key)
-
-	keyChangeEventResponse = &WatchKeyChangeResponse{
-		Event:   make(chan *KeyChangeEvent, 250),
-		Watcher: watcher,
-	}
-
-	go func() {
-		for ch := range watchChans {
-			if ch.Canceled {
-				goto End
-			}
-			for _, event := range ch.Events {
-				etcd.handleKeyChangeEvent(event, keyChangeEventResponse.Event)
-			}
+// 删除key，opts可以指定前缀等，返回删除的key数量
+func (etcd *Etcd) DeleteWithContext(ctx context.Context, key string, opts ...clientv3.OpOption) (deleted int64, err error) {
+	ctx, span := startEtcdSpan(ctx, "Delete", key)
+	defer func() { EndSpan(span, err) }()
+	err = etcd.do(ctx, "Delete", key, func(ctx context.Context) error {
+		deleteResponse, err := etcd.kv.Delete(ctx, key, opts...)
+		if err == nil {
+			deleted = deleteResponse.Deleted
 		}
-	End:
-		// log.Println("the watcher lose for key:", key)
-	}()
+		return err
+	})
 	return
 }
 
-// watch一个key前缀
-func (etcd *Etcd) WatchWithPrefixKey(prefixKey string) (keyChangeEventResponse *WatchKeyChangeResponse) {
-	watcher := clientv3.NewWatcher(etcd.client)
-	watchChans := watcher.Watch(context.Background(), prefixKey, clientv3.WithPrefix())
-
-	keyChangeEventResponse = &WatchKeyChangeResponse{
-		Event:   make(chan *KeyChangeEvent, 250),
-		Watcher: nil,
-	}
-
-	go func() {
-		for ch := range watchChans {
-			if ch.Canceled {
-				goto End
-			}
-			for _, event := range ch.Events {
-				etcd.handleKeyChangeEvent(event, keyChangeEventResponse.Event)
-			}
+// 申请租约
+func (etcd *Etcd) grant(ctx context.Context, lease clientv3.Lease, key string, ttl int64) (leaseID clientv3.LeaseID, err error) {
+	err = etcd.do(ctx, "Grant", key, func(ctx context.Context) error {
+		grantResponse, err := lease.Grant(ctx, ttl)
+		if err == nil {
+			leaseID = grantResponse.ID
 		}
-	End:
-		// log
-	}()
+		return err
+	})
 	return
 }
 
 // 创建一个指定时间的临时key
 func (etcd *Etcd) TxWithTTL(key, value string, ttl int64) (txResponse *TxResponse, err error) {
+	return etcd.TxWithTTLWithContext(context.Background(), key, value, ttl)
+}
+
+// 创建一个指定时间的临时key，span作为ctx中span的子span
+func (etcd *Etcd) TxWithTTLWithContext(ctx context.Context, key, value string, ttl int64) (txResponse *TxResponse, err error) {
 	var (
-		txnResponse *clientv3.TxnResponse
-		leaseID     clientv3.LeaseID
-		v           []byte
+		leaseID clientv3.LeaseID
+		v       []byte
 	)
+	ctx, span := startEtcdSpan(ctx, "TxWithTTL", key)
+	defer func() { EndSpan(span, err) }()
 	lease := clientv3.NewLease(etcd.client)
-	grantResponse, err := lease.Grant(context.Background(), ttl)
-	if err != nil {
+	if leaseID, err = etcd.grant(ctx, lease, key, ttl); err != nil {
+		_ = lease.Close()
 		return
 	}
-	leaseID = grantResponse.ID
-
-	ctx, cancelFunc := context.WithTimeout(context.Background(), etcd.timeout)
-	defer cancelFunc()
 
-	txn := etcd.client.Txn(ctx)
-	txnResponse, err = txn.If(clientv3.Compare(clientv3.Version(key), "=", 0)).
-		Then(clientv3.OpPut(key, value, clientv3.WithLease(leaseID))).
-		Commit()
+	_, err = etcd.TxnWithContext(ctx,
+		[]clientv3.Cmp{clientv3.Compare(clientv3.Version(key), "=", 0)},
+		[]clientv3.Op{clientv3.OpPut(key, value, clientv3.WithLease(leaseID))})
 
-	if err != nil {
+	if err != nil && !IsConflict(err) {
 		_ = lease.Close()
 		return
 	}
@@ -302,11 +375,13 @@ func (etcd *Etcd) TxWithTTL(key, value string, ttl int64) (txResponse *TxRespons
 		LeaseID: leaseID,
 		Lease:   lease,
 	}
-	if txnResponse.Succeeded {
+	if err == nil {
 		txResponse.Success = true
 	} else {
 		_ = lease.Close()
-		v, err = etcd.Get(key)
+		if v, err = etcd.GetWithContext(ctx, key); IsNotFound(err) {
+			err = nil
+		}
 		if err != nil {
 			return
 		}
@@ -319,23 +394,31 @@ func (etcd *Etcd) TxWithTTL(key, value string, ttl int64) (txResponse *TxRespons
 
 // 创建一个不间断续约的临时key
 func (etcd *Etcd) TxKeepaliveWithTTL(key, value string, ttl int64) (txResponse *TxResponse, err error) {
+	return etcd.TxKeepaliveWithTTLWithContext(context.Background(), key, value, ttl)
+}
+
+// 创建一个不间断续约的临时key，span作为ctx中span的子span，续约不受ctx影响
+func (etcd *Etcd) TxKeepaliveWithTTLWithContext(ctx context.Context, key, value string, ttl int64) (txResponse *TxResponse, err error) {
 	var (
 		txnResponse   *clientv3.TxnResponse
 		leaseId       clientv3.LeaseID
 		aliveResponse <-chan *clientv3.LeaseKeepAliveResponse
 		v             []byte
 	)
+	ctx, span := startEtcdSpan(ctx, "TxKeepaliveWithTTL", key)
+	defer func() { EndSpan(span, err) }()
 	lease := clientv3.NewLease(etcd.client)
 
-	grantResponse, err := lease.Grant(context.Background(), ttl)
-	if err != nil {
+	if leaseId, err = etcd.grant(ctx, lease, key, ttl); err != nil {
+		_ = lease.Close()
 		return
 	}
-	leaseId = grantResponse.ID
 	if aliveResponse, err = lease.KeepAlive(context.Background(), leaseId); err != nil {
+		_ = lease.Close()
 		return
 	}
 
+	done := make(chan struct{})
 	go func() {
 		for ch := range aliveResponse {
 			if ch == nil {
@@ -343,18 +426,15 @@ func (etcd *Etcd) TxKeepaliveWithTTL(key, value string, ttl int64) (txResponse *
 			}
 		}
 	End:
-		// log
+		close(done)
 	}()
 
-	ctx, cancelFunc := context.WithTimeout(context.Background(), etcd.timeout)
-	defer cancelFunc()
+	txnResponse, err = etcd.TxnWithContext(ctx,
+		[]clientv3.Cmp{clientv3.Compare(clientv3.Version(key), "=", 0)},
+		[]clientv3.Op{clientv3.OpPut(key, value, clientv3.WithLease(leaseId))},
+		clientv3.OpGet(key))
 
-	txn := etcd.client.Txn(ctx)
-	txnResponse, err = txn.If(clientv3.Compare(clientv3.Version(key), "=", 0)).
-		Then(clientv3.OpPut(key, value, clientv3.WithLease(leaseId))).
-		Else(clientv3.OpGet(key)).Commit()
-
-	if err != nil {
+	if err != nil && !IsConflict(err) {
 		_ = lease.Close()
 		return
 	}
@@ -363,14 +443,17 @@ func (etcd *Etcd) TxKeepaliveWithTTL(key, value string, ttl int64) (txResponse *
 		Lease:   lease,
 	}
 
-	if txnResponse.Succeeded {
+	if err == nil {
 		txResponse.Success = true
+		txResponse.Done = done
 	} else {
 		_ = lease.Close()
 		txResponse.Success = false
-		if v, err = etcd.Get(key); err != nil {
-			return
+		// 比较和读取之间key可能已经被删除
+		if kvs := txnResponse.Responses[0].GetResponseRange().Kvs; len(kvs) > 0 {
+			v = kvs[0].Value
 		}
+		err = nil
 		txResponse.Key = key
 		txResponse.Value = string(v)
 	}
@@ -380,22 +463,3 @@ func (etcd *Etcd) TxKeepaliveWithTTL(key, value string, ttl int64) (txResponse *
 func (etcd *Etcd) Close() {
 	etcd.client.Close()
 }
-
-func (etcd *Etcd) handleKeyChangeEvent(event *clientv3.Event, events chan *KeyChangeEvent) {
-	changeEvent := &KeyChangeEvent{
-		Key: string(event.Kv.Key),
-	}
-
-	switch event.Type {
-	case mvccpb.PUT:
-		if event.IsCreate() {
-			changeEvent.Type = KeyCreateChangeEvent
-		} else {
-			changeEvent.Type = KeyUpdateChangeEvent
-		}
-		changeEvent.Value = event.Kv.Value
-	case mvccpb.DELETE:
-		changeEvent.Type = KeyDeleteChangeEvent
-	}
-	events <- changeEvent
-}
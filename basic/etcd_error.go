@@ -0,0 +1,173 @@
+package basic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/coreos/etcd/etcdserver/api/v3rpc/rpctypes"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+// etcd错误的分类
+type EtcdErrorCode int
+
+const (
+	EtcdErrorUnknown  EtcdErrorCode = iota // 其他错误，如权限不足、参数不正确
+	EtcdErrorNotFound                      // key不存在
+	EtcdErrorTimeout                       // 请求超时，请求可能已经执行
+	EtcdErrorConflict                      // 事务的比较条件不满足
+)
+
+func (c EtcdErrorCode) String() string {
+	switch c {
+	case EtcdErrorNotFound:
+		return "not found"
+	case EtcdErrorTimeout:
+		return "timeout"
+	case EtcdErrorConflict:
+		return "conflict"
+	default:
+		return "unknown"
+	}
+}
+
+// *WithContext方法返回的错误
+type EtcdError struct {
+	Code EtcdErrorCode
+	Op   string
+	Key  string
+	Err  error // 原始错误，NotFound和Conflict时为nil
+}
+
+func (e *EtcdError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("etcd %s %s: %s", e.Op, e.Key, e.Code)
+	}
+	return fmt.Sprintf("etcd %s %s: %s: %v", e.Op, e.Key, e.Code, e.Err)
+}
+
+func (e *EtcdError) Unwrap() error {
+	return e.Err
+}
+
+func IsNotFound(err error) bool {
+	return etcdErrorCode(err) == EtcdErrorNotFound
+}
+
+func IsTimeout(err error) bool {
+	return etcdErrorCode(err) == EtcdErrorTimeout
+}
+
+func IsConflict(err error) bool {
+	return etcdErrorCode(err) == EtcdErrorConflict
+}
+
+func etcdErrorCode(err error) EtcdErrorCode {
+	var e *EtcdError
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return EtcdErrorUnknown
+}
+
+func newEtcdError(op, key string, err error) error {
+	code := EtcdErrorUnknown
+	if isTimeout(err) {
+		code = EtcdErrorTimeout
+	}
+	return &EtcdError{Code: code, Op: op, Key: key, Err: err}
+}
+
+func grpcCode(err error) codes.Code {
+	if e, ok := err.(rpctypes.EtcdError); ok {
+		return e.Code()
+	}
+	return status.Code(err)
+}
+
+func isTimeout(err error) bool {
+	switch err {
+	case context.DeadlineExceeded, rpctypes.ErrTimeout, rpctypes.ErrTimeoutDueToLeaderFail, rpctypes.ErrTimeoutDueToConnectionLost:
+		return true
+	}
+	return grpcCode(err) == codes.DeadlineExceeded
+}
+
+// etcd暂时不可用(没有leader、leader切换、连接断开)或单次请求超时的错误可以重试
+func isRetryable(err error) bool {
+	if isTimeout(err) {
+		return true
+	}
+	return grpcCode(err) == codes.Unavailable
+}
+
+// 请求一定没有被执行的错误，与clientv3对修改请求的重试条件(isSafeRetryMutableRPC)一致：
+// 没有leader时etcd拒绝请求，没有可用的地址或连接时请求没有发出。
+// 其他Unavailable错误(如请求发出后连接断开)和超时一样，请求可能已经执行成功，不能确定
+func isUnapplied(err error) bool {
+	if grpcCode(err) != codes.Unavailable {
+		return false
+	}
+	switch rpctypes.ErrorDesc(err) {
+	case rpctypes.ErrorDesc(rpctypes.ErrGRPCNoLeader), "there is no address available", "there is no connection available":
+		return true
+	}
+	return false
+}
+
+// 重试策略，maxRetries为0时不重试
+type retryPolicy struct {
+	maxRetries int
+	backoff    time.Duration
+	maxBackoff time.Duration
+}
+
+// 执行一次etcd请求，每次尝试的超时时间为etcd.timeout，遇到可重试的错误时按指数退避重试，直到ctx结束
+func (etcd *Etcd) do(ctx context.Context, op, key string, fn func(ctx context.Context) error) error {
+	return etcd.doRetryIf(ctx, op, key, isRetryable, fn)
+}
+
+// 同do，只在retryable返回true时重试
+func (etcd *Etcd) doRetryIf(ctx context.Context, op, key string, retryable func(error) bool, fn func(ctx context.Context) error) error {
+	backoff := etcd.retry.backoff
+	for attempt := 1; ; attempt++ {
+		err := etcd.attempt(ctx, fn)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return newEtcdError(op, key, ctx.Err())
+		}
+		if attempt > etcd.retry.maxRetries || !retryable(err) {
+			return newEtcdError(op, key, err)
+		}
+
+		zap.S().Warnw("etcd请求失败，等待重试", "op", op, "key", key, "attempt", attempt, "backoff", backoff, "err", err)
+		trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(
+			attribute.Int("etcd.attempt", attempt),
+			attribute.String("etcd.error", err.Error()),
+		))
+		select {
+		case <-ctx.Done():
+			return newEtcdError(op, key, ctx.Err())
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > etcd.retry.maxBackoff {
+			backoff = etcd.retry.maxBackoff
+		}
+	}
+}
+
+func (etcd *Etcd) attempt(ctx context.Context, fn func(ctx context.Context) error) error {
+	if etcd.timeout <= 0 {
+		return fn(ctx)
+	}
+	ctx, cancelFunc := context.WithTimeout(ctx, etcd.timeout)
+	defer cancelFunc()
+	return fn(ctx)
+}
@@ -0,0 +1,112 @@
+package basic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/coreos/etcd/etcdserver/api/v3rpc/rpctypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+func TestEtcdError_Is(t *testing.T) {
+	notFound := &EtcdError{Code: EtcdErrorNotFound, Op: "Get", Key: "foo"}
+	if !IsNotFound(notFound) || IsTimeout(notFound) || IsConflict(notFound) {
+		t.Fatal("NotFound判断不正确")
+	}
+	wrapped := fmt.Errorf("读取配置失败: %w", &EtcdError{Code: EtcdErrorConflict, Op: "Txn", Key: "foo"})
+	if !IsConflict(wrapped) {
+		t.Fatal("包装后的Conflict判断不正确")
+	}
+	timeout := newEtcdError("Put", "foo", rpctypes.ErrTimeout)
+	if !IsTimeout(timeout) || !errors.Is(timeout, rpctypes.ErrTimeout) {
+		t.Fatal("Timeout判断不正确")
+	}
+	if IsTimeout(newEtcdError("Put", "foo", rpctypes.ErrPermissionDenied)) || IsNotFound(errors.New("foo")) {
+		t.Fatal("其他错误不应该被分类")
+	}
+}
+
+func TestEtcd_Do(t *testing.T) {
+	etcd := &Etcd{retry: retryPolicy{maxRetries: 2, backoff: time.Millisecond, maxBackoff: 2 * time.Millisecond}}
+	failures := func(n int, err error) (func(ctx context.Context) error, *int) {
+		calls := 0
+		return func(ctx context.Context) error {
+			if calls++; calls <= n {
+				return err
+			}
+			return nil
+		}, &calls
+	}
+
+	// 可重试的错误在重试次数内成功
+	fn, calls := failures(2, rpctypes.ErrGRPCNoLeader)
+	if err := etcd.do(context.Background(), "Get", "foo", fn); err != nil || *calls != 3 {
+		t.Fatalf("应该重试后成功: %v %d", err, *calls)
+	}
+	// 超过重试次数
+	fn, calls = failures(3, rpctypes.ErrGRPCNoLeader)
+	if err := etcd.do(context.Background(), "Get", "foo", fn); err == nil || *calls != 3 {
+		t.Fatalf("超过重试次数应该返回错误: %v %d", err, *calls)
+	}
+	// 不可重试的错误
+	fn, calls = failures(1, rpctypes.ErrGRPCPermissionDenied)
+	err := etcd.do(context.Background(), "Get", "foo", fn)
+	if err == nil || *calls != 1 || IsTimeout(err) {
+		t.Fatalf("不可重试的错误不应该重试: %v %d", err, *calls)
+	}
+	// 单次请求超时后重试
+	etcd.timeout = time.Millisecond
+	fn, calls = failures(1, nil)
+	slow := func(ctx context.Context) error {
+		if *calls == 0 {
+			<-ctx.Done()
+			*calls++
+			return ctx.Err()
+		}
+		return fn(ctx)
+	}
+	if err := etcd.do(context.Background(), "Get", "foo", slow); err != nil {
+		t.Fatalf("单次请求超时后应该重试: %v", err)
+	}
+	// 事务超时时可能已经执行，不重试，没有leader时重试
+	fn, calls = failures(1, nil)
+	timeout := func(ctx context.Context) error {
+		if *calls == 0 {
+			<-ctx.Done()
+			*calls++
+			return ctx.Err()
+		}
+		return fn(ctx)
+	}
+	if err := etcd.doRetryIf(context.Background(), "Txn", "foo", isUnapplied, timeout); !IsTimeout(err) || *calls != 1 {
+		t.Fatalf("事务超时后不应该重试: %v %d", err, *calls)
+	}
+	fn, calls = failures(1, rpctypes.ErrGRPCNoLeader)
+	if err := etcd.doRetryIf(context.Background(), "Txn", "foo", isUnapplied, fn); err != nil || *calls != 2 {
+		t.Fatalf("没有leader时事务应该重试: %v %d", err, *calls)
+	}
+	// clientv3返回的是转换后的错误
+	fn, calls = failures(1, rpctypes.ErrNoLeader)
+	if err := etcd.doRetryIf(context.Background(), "Txn", "foo", isUnapplied, fn); err != nil || *calls != 2 {
+		t.Fatalf("没有leader时事务应该重试: %v %d", err, *calls)
+	}
+	fn, calls = failures(1, status.Error(codes.Unavailable, "there is no connection available"))
+	if err := etcd.doRetryIf(context.Background(), "Txn", "foo", isUnapplied, fn); err != nil || *calls != 2 {
+		t.Fatalf("没有可用连接时事务应该重试: %v %d", err, *calls)
+	}
+	// 连接中断等其他Unavailable错误时事务可能已经执行，不重试
+	fn, calls = failures(1, status.Error(codes.Unavailable, "transport is closing"))
+	if err := etcd.doRetryIf(context.Background(), "Txn", "foo", isUnapplied, fn); err == nil || *calls != 1 {
+		t.Fatalf("连接中断时事务不应该重试: %v %d", err, *calls)
+	}
+	// 调用方的ctx结束后不再重试
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	cancelFunc()
+	fn, calls = failures(3, rpctypes.ErrGRPCNoLeader)
+	if err := etcd.do(ctx, "Get", "foo", fn); !errors.Is(err, context.Canceled) || *calls != 1 {
+		t.Fatalf("ctx结束后不应该重试: %v %d", err, *calls)
+	}
+}
@@ -0,0 +1,192 @@
+package basic
+
+import (
+	"context"
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/clientv3/concurrency"
+	"sync"
+)
+
+// 分布式锁和选举共用的session，session的租约失效(进程退出或与etcd断开超过ttl)后Done被关闭，
+// 此时持有的锁或leader身份已经失效
+type etcdSession struct {
+	key     string
+	session *concurrency.Session
+}
+
+func (etcd *Etcd) newSession(key string, ttl int) (*etcdSession, error) {
+	// 租约通过etcd.grant申请，与其他请求使用相同的超时和重试
+	leaseID, err := etcd.grant(context.Background(), etcd.client, key, int64(ttl))
+	if err != nil {
+		return nil, err
+	}
+	session, err := concurrency.NewSession(etcd.client, concurrency.WithLease(leaseID), concurrency.WithTTL(ttl))
+	if err != nil {
+		return nil, newEtcdError("NewSession", key, err)
+	}
+	return &etcdSession{key: key, session: session}, nil
+}
+
+func (s *etcdSession) Done() <-chan struct{} {
+	return s.session.Done()
+}
+
+// 撤销session的租约，持有的锁和leader身份随之释放
+func (s *etcdSession) Close() error {
+	if err := s.session.Close(); err != nil {
+		return newEtcdError("Revoke", s.key, err)
+	}
+	return nil
+}
+
+// 基于etcd的分布式锁，同一个Mutex不能被多个goroutine同时使用
+type Mutex struct {
+	*etcdSession
+	mutex *concurrency.Mutex
+}
+
+// 创建分布式锁，ttl为session租约的时间(s)，持有锁的节点异常退出后最多ttl秒锁被释放，不再使用时调用Close
+func (etcd *Etcd) NewMutex(key string, ttl int) (*Mutex, error) {
+	session, err := etcd.newSession(key, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return &Mutex{etcdSession: session, mutex: concurrency.NewMutex(session.session, key)}, nil
+}
+
+// 阻塞直到获得锁，ctx结束时放弃等待
+func (m *Mutex) Lock(ctx context.Context) (err error) {
+	ctx, span := startEtcdSpan(ctx, "Lock", m.key)
+	defer func() { EndSpan(span, err) }()
+	if err = m.mutex.Lock(ctx); err != nil {
+		return newEtcdError("Lock", m.key, err)
+	}
+	return nil
+}
+
+func (m *Mutex) Unlock(ctx context.Context) (err error) {
+	ctx, span := startEtcdSpan(ctx, "Unlock", m.key)
+	defer func() { EndSpan(span, err) }()
+	if err = m.mutex.Unlock(ctx); err != nil {
+		return newEtcdError("Unlock", m.key, err)
+	}
+	return nil
+}
+
+// 持有锁执行fn，锁失效时fn的ctx被取消，fn返回后释放锁
+func (etcd *Etcd) WithLock(ctx context.Context, key string, ttl int, fn func(ctx context.Context) error) error {
+	mutex, err := etcd.NewMutex(key, ttl)
+	if err != nil {
+		return err
+	}
+	// 撤销租约时锁随之释放，不需要单独Unlock
+	defer func() { _ = mutex.Close() }()
+	if err = mutex.Lock(ctx); err != nil {
+		return err
+	}
+
+	ctx, cancelFunc := context.WithCancel(ctx)
+	defer cancelFunc()
+	go func() {
+		select {
+		case <-mutex.Done():
+			cancelFunc()
+		case <-ctx.Done():
+		}
+	}()
+	return fn(ctx)
+}
+
+// 基于etcd的leader选举，同一个key下同时只有一个leader
+type Election struct {
+	*etcdSession
+	election *concurrency.Election
+	mutex    sync.Mutex
+	leader   bool
+}
+
+// 创建选举，ttl为session租约的时间(s)，leader异常退出后最多ttl秒其他节点可以成为leader，不再使用时调用Close
+func (etcd *Etcd) NewElection(key string, ttl int) (*Election, error) {
+	session, err := etcd.newSession(key, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return &Election{etcdSession: session, election: concurrency.NewElection(session.session, key)}, nil
+}
+
+// 阻塞直到成为leader，value为leader公布的值，如节点地址。ctx结束时放弃竞选
+func (e *Election) Campaign(ctx context.Context, value string) (err error) {
+	ctx, span := startEtcdSpan(ctx, "Campaign", e.key)
+	defer func() { EndSpan(span, err) }()
+	if err = e.election.Campaign(ctx, value); err != nil {
+		return newEtcdError("Campaign", e.key, err)
+	}
+	e.setLeader(true)
+	return nil
+}
+
+// 放弃leader身份，不是leader时什么也不做
+func (e *Election) Resign(ctx context.Context) (err error) {
+	ctx, span := startEtcdSpan(ctx, "Resign", e.key)
+	defer func() { EndSpan(span, err) }()
+	e.setLeader(false)
+	if err = e.election.Resign(ctx); err != nil {
+		return newEtcdError("Resign", e.key, err)
+	}
+	return nil
+}
+
+// 当前leader公布的值，没有leader时返回NotFound错误
+func (e *Election) Leader(ctx context.Context) (value string, err error) {
+	ctx, span := startEtcdSpan(ctx, "Leader", e.key)
+	defer func() { EndSpan(span, err) }()
+	getResponse, err := e.election.Leader(ctx)
+	if err == concurrency.ErrElectionNoLeader {
+		return "", &EtcdError{Code: EtcdErrorNotFound, Op: "Leader", Key: e.key}
+	}
+	if err != nil {
+		return "", newEtcdError("Leader", e.key, err)
+	}
+	return string(getResponse.Kvs[0].Value), nil
+}
+
+// 当前节点是否是leader，session失效后不再是leader
+func (e *Election) IsLeader() bool {
+	select {
+	case <-e.Done():
+		return false
+	default:
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.leader
+}
+
+func (e *Election) setLeader(leader bool) {
+	e.mutex.Lock()
+	e.leader = leader
+	e.mutex.Unlock()
+}
+
+// 每次leader变化时发送新leader公布的值，ctx结束后关闭
+func (e *Election) Observe(ctx context.Context) <-chan string {
+	leaders := make(chan string)
+	go func() {
+		defer close(leaders)
+		for getResponse := range e.election.Observe(ctx) {
+			select {
+			case leaders <- leaderValue(getResponse):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return leaders
+}
+
+func leaderValue(getResponse clientv3.GetResponse) string {
+	if len(getResponse.Kvs) == 0 {
+		return ""
+	}
+	return string(getResponse.Kvs[0].Value)
+}
@@ -0,0 +1,131 @@
+package basic
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEtcd_Mutex(t *testing.T) {
+	etcd := testEtcd(t)
+	defer etcd.Close()
+	key := fmt.Sprintf("test/lock/%d", time.Now().UnixNano())
+
+	first, err := etcd.NewMutex(key, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	second, err := etcd.NewMutex(key, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+
+	if err = first.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancelFunc()
+	if err = second.Lock(ctx); !IsTimeout(err) {
+		t.Fatal("锁被占用时应该等待到超时", err)
+	}
+
+	// 持有锁的session失效后锁被释放
+	if err = first.Close(); err != nil {
+		t.Fatal(err)
+	}
+	<-first.Done()
+	if err = second.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err = second.Unlock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEtcd_WithLock(t *testing.T) {
+	etcd := testEtcd(t)
+	defer etcd.Close()
+	key := fmt.Sprintf("test/lock/%d", time.Now().UnixNano())
+	counter := key + "/counter"
+	defer func() { _ = etcd.DeleteWithPrefixKey(key) }()
+
+	// 没有锁时并发的读取再写入会丢失更新
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := etcd.WithLock(context.Background(), key, 5, func(ctx context.Context) error {
+				value, err := etcd.GetWithContext(ctx, counter)
+				if err != nil && !IsNotFound(err) {
+					return err
+				}
+				n, _ := strconv.Atoi(string(value))
+				return etcd.PutWithContext(ctx, counter, strconv.Itoa(n+1))
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if value, err := etcd.Get(counter); err != nil || string(value) != "10" {
+		t.Fatal("持有锁时的更新不应该丢失", string(value), err)
+	}
+}
+
+func TestEtcd_Election(t *testing.T) {
+	etcd := testEtcd(t)
+	defer etcd.Close()
+	key := fmt.Sprintf("test/election/%d", time.Now().UnixNano())
+
+	first, err := etcd.NewElection(key, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	if _, err = first.Leader(context.Background()); !IsNotFound(err) {
+		t.Fatal("没有leader时应该返回NotFound", err)
+	}
+	if err = first.Campaign(context.Background(), "a"); err != nil {
+		t.Fatal(err)
+	}
+	if leader, err := first.Leader(context.Background()); err != nil || leader != "a" || !first.IsLeader() {
+		t.Fatal("leader不正确", leader, err)
+	}
+
+	second, err := etcd.NewElection(key, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	leaders := second.Observe(ctx)
+	if leader := <-leaders; leader != "a" {
+		t.Fatal("leader不正确", leader)
+	}
+	elected := make(chan error, 1)
+	go func() { elected <- second.Campaign(context.Background(), "b") }()
+	select {
+	case err = <-elected:
+		t.Fatal("已有leader时不应该选举成功", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// leader放弃后其他节点成为leader
+	if err = first.Resign(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err = <-elected; err != nil {
+		t.Fatal(err)
+	}
+	if leader := <-leaders; leader != "b" || first.IsLeader() || !second.IsLeader() {
+		t.Fatal("leader不正确", leader)
+	}
+}
@@ -1,6 +1,13 @@
 package basic
 
 import (
+	"context"
+	"fmt"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -204,3 +211,137 @@ func TestEtcd_TxKeepaliveWithTTL(t *testing.T) {
 	time.Sleep(time.Minute)
 	_ = txResponse.Lease.Close()
 }
+
+func TestEtcdConfig_ClientConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "msnowflake-etcd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.pem")
+	if err = ioutil.WriteFile(caFile, []byte(testCertPEM), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	clientConfig, err := EtcdConfig{Endpoints: []string{"127.0.0.1:2379"}}.clientConfig()
+	if err != nil || clientConfig.TLS != nil || len(clientConfig.Username) != 0 {
+		t.Fatal("没有配置TLS和用户时不应该开启", err)
+	}
+
+	config := EtcdConfig{
+		Endpoints: []string{"127.0.0.1:2379"},
+		TLS:       TLSConfig{CAFile: caFile},
+		Username:  "msnowflake",
+		Password:  "secret",
+	}
+	if clientConfig, err = config.clientConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if clientConfig.TLS == nil || clientConfig.TLS.RootCAs == nil || clientConfig.Username != "msnowflake" || clientConfig.Password != "secret" {
+		t.Fatal("TLS和用户配置没有传递给etcd客户端")
+	}
+
+	if _, err = (EtcdConfig{Password: "secret"}).clientConfig(); err == nil {
+		t.Error("只配置密码时应该返回错误")
+	}
+	if _, err = (EtcdConfig{TLS: TLSConfig{CAFile: filepath.Join(dir, "missing.pem")}}).clientConfig(); err == nil {
+		t.Error("CA文件不存在时应该返回错误")
+	}
+}
+
+func TestEtcdConfig_GetNamespace(t *testing.T) {
+	for namespace, expected := range map[string]string{"": DefaultEtcdNamespace, "staging": "staging/", "tenant/a/": "tenant/a/"} {
+		if actual := (EtcdConfig{Namespace: namespace}).GetNamespace(); actual != expected {
+			t.Errorf("%q: %s", namespace, actual)
+		}
+	}
+}
+
+func TestEtcd_Namespace(t *testing.T) {
+	root := testEtcd(t)
+	defer root.Close()
+	namespace := fmt.Sprintf("test/namespace/%d/", time.Now().UnixNano())
+	config := EtcdConfig{Endpoints: root.endpoints, ConnectTimeout: 5 * time.Second, Namespace: root.Namespace() + namespace + "a"}
+	a, err := NewEtcd(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	config.Namespace = root.Namespace() + namespace + "b"
+	b, err := NewEtcd(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	defer func() { _ = root.DeleteWithPrefixKey(namespace) }()
+
+	watch := b.Watch("worker/1")
+	defer watch.CancelFunc()
+	if err = a.Put("worker/1", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := b.Get("worker/1"); err != nil || value != nil {
+		t.Fatal("不同命名空间的key不应该互相可见", string(value), err)
+	}
+	if value, err := root.Get(namespace + "a/worker/1"); err != nil || string(value) != "a" {
+		t.Fatal("key应该带上命名空间的前缀", string(value), err)
+	}
+
+	// 锁和watch同样在命名空间内
+	if err = b.Put("worker/1", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if event := nextEvent(t, watch); event.Key != "worker/1" || string(event.Value) != "b" {
+		t.Fatalf("事件不正确: %+v", event)
+	}
+	lockA, err := a.NewMutex("lock", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lockA.Close()
+	lockB, err := b.NewMutex("lock", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lockB.Close()
+	if err = lockA.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second)
+	defer cancelFunc()
+	if err = lockB.Lock(ctx); err != nil {
+		t.Fatal("不同命名空间的锁不应该互斥", err)
+	}
+	if _, values, _ := root.GetWithPrefixKey(namespace + "b/lock/"); len(values) != 1 {
+		t.Fatal("锁的key应该带上命名空间的前缀")
+	}
+}
+
+func TestEtcd_TxKeepaliveWithTTLWithContext(t *testing.T) {
+	etcd := testEtcd(t)
+	defer etcd.Close()
+	recorder := tracetest.NewSpanRecorder()
+	old := etcdTracer
+	etcdTracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	defer func() { etcdTracer = old }()
+
+	ctx, parent := etcdTracer.Start(context.Background(), "parent")
+	key := fmt.Sprintf("test/ttl/%d", time.Now().UnixNano())
+	txResponse, err := etcd.TxKeepaliveWithTTLWithContext(ctx, key, "1", 5)
+	if err != nil || !txResponse.Success {
+		t.Fatal("创建临时key失败", err)
+	}
+	defer func() { _, _ = txResponse.Lease.Revoke(context.Background(), txResponse.LeaseID) }()
+	parent.End()
+
+	// 所有etcd操作的span都属于上游的trace
+	spans := recorder.Ended()
+	if len(spans) < 3 {
+		t.Fatalf("span数量不正确: %d", len(spans))
+	}
+	for _, span := range spans {
+		if span.SpanContext().TraceID() != parent.SpanContext().TraceID() {
+			t.Errorf("span %s 不属于上游的trace", span.Name())
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package basic
+
+import (
+	"context"
+	"errors"
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+	"go.uber.org/zap"
+	"time"
+)
+
+// 定义key变更事件常量
+const (
+	KeyCreateChangeEvent = iota
+	KeyUpdateChangeEvent
+	KeyDeleteChangeEvent
+	// 需要的revision已经被压缩，Revision之前的事件可能丢失，调用方需要重新读取完整数据
+	KeyCompactedChangeEvent
+)
+
+// 没有配置重试时重新watch前等待的时间
+const defaultWatchRetryBackoff = 100 * time.Millisecond
+
+// watch被关闭(Watcher被关闭或etcd连接关闭)
+var ErrWatchClosed = errors.New("etcd watch已关闭")
+
+type KeyChangeEvent struct {
+	Type     int
+	Key      string
+	Value    []byte
+	Revision int64
+}
+
+// watch结束(调用CancelFunc、ctx结束或遇到不可重试的错误)后Event被关闭，之后Err返回结束的原因
+type WatchKeyChangeResponse struct {
+	Event      chan *KeyChangeEvent
+	CancelFunc context.CancelFunc
+	Watcher    clientv3.Watcher
+	err        error
+}
+
+func (w *WatchKeyChangeResponse) Err() error {
+	return w.err
+}
+
+type watchOptions struct {
+	prefix   bool
+	revision int64
+}
+
+type WatchOption func(*watchOptions)
+
+// watch一个key前缀
+func WatchPrefix() WatchOption {
+	return func(options *watchOptions) {
+		options.prefix = true
+	}
+}
+
+// 从指定的revision开始watch，为0时从当前revision开始
+func WatchFromRevision(revision int64) WatchOption {
+	return func(options *watchOptions) {
+		options.revision = revision
+	}
+}
+
+// watch 一个key
+func (etcd *Etcd) Watch(key string) (keyChangeEventResponse *WatchKeyChangeResponse) {
+	return etcd.WatchWithContext(context.Background(), key)
+}
+
+// watch一个key前缀
+func (etcd *Etcd) WatchWithPrefixKey(prefixKey string) (keyChangeEventResponse *WatchKeyChangeResponse) {
+	return etcd.WatchWithContext(context.Background(), prefixKey, WatchPrefix())
+}
+
+// watch一个key，连接断开、leader切换后从最后收到的revision继续watch，不会漏掉事件。
+// 需要的revision已经被压缩时发送KeyCompactedChangeEvent并从压缩后的revision继续
+func (etcd *Etcd) WatchWithContext(ctx context.Context, key string, opts ...WatchOption) *WatchKeyChangeResponse {
+	options := watchOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	ctx, cancelFunc := context.WithCancel(ctx)
+	w := &WatchKeyChangeResponse{
+		Event:      make(chan *KeyChangeEvent, 250),
+		CancelFunc: cancelFunc,
+		Watcher:    etcd.newWatcher(),
+	}
+	go etcd.watch(ctx, w, key, options)
+	return w
+}
+
+func (etcd *Etcd) watch(ctx context.Context, w *WatchKeyChangeResponse, key string, options watchOptions) {
+	defer func() {
+		_ = w.Watcher.Close()
+		w.CancelFunc()
+		close(w.Event)
+	}()
+	// revision为下一个需要接收的revision
+	revision := options.revision
+	minBackoff := etcd.retry.backoff
+	if minBackoff <= 0 {
+		minBackoff = defaultWatchRetryBackoff
+	}
+	maxBackoff := maxDuration(etcd.retry.maxBackoff, minBackoff)
+	backoff := minBackoff
+	for {
+		opOpts := []clientv3.OpOption{clientv3.WithCreatedNotify(), clientv3.WithProgressNotify()}
+		if options.prefix {
+			opOpts = append(opOpts, clientv3.WithPrefix())
+		}
+		if revision > 0 {
+			opOpts = append(opOpts, clientv3.WithRev(revision))
+		}
+		// 没有leader时不会收到事件，要求有leader以便及时发现并重新watch
+		watchChan := w.Watcher.Watch(clientv3.WithRequireLeader(ctx), key, opOpts...)
+
+		received, err := etcd.receive(ctx, w, key, watchChan, &revision)
+		if ctx.Err() != nil {
+			w.err = ctx.Err()
+			return
+		}
+		if err == nil {
+			continue
+		}
+		if !isRetryable(err) {
+			w.err = newEtcdError("Watch", key, err)
+			zap.S().Errorw("etcd watch结束", "key", key, "revision", revision, "err", err)
+			return
+		}
+
+		if received {
+			backoff = minBackoff
+		}
+		zap.S().Warnw("etcd watch中断，等待重新watch", "key", key, "revision", revision, "backoff", backoff, "err", err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// 接收事件直到watchChan关闭，返回nil时需要立即从revision重新watch，received表示是否收到过响应。
+// 返回的是etcd的原始错误，用于判断能否重试
+func (etcd *Etcd) receive(ctx context.Context, w *WatchKeyChangeResponse, key string, watchChan clientv3.WatchChan, revision *int64) (received bool, err error) {
+	for resp := range watchChan {
+		received = true
+		if resp.CompactRevision != 0 {
+			zap.S().Warnw("etcd watch的revision已被压缩，可能丢失事件", "key", key, "revision", *revision, "compactRevision", resp.CompactRevision)
+			*revision = resp.CompactRevision
+			return received, w.send(ctx, &KeyChangeEvent{Type: KeyCompactedChangeEvent, Key: key, Revision: resp.CompactRevision})
+		}
+		if err = resp.Err(); err != nil {
+			return
+		}
+		if len(resp.Events) == 0 {
+			// 创建成功或进度通知，Header.Revision之前的事件都已经收到
+			if (*revision == 0 || resp.IsProgressNotify()) && resp.Header.Revision >= *revision {
+				*revision = resp.Header.Revision + 1
+			}
+			continue
+		}
+		for _, event := range resp.Events {
+			if err = w.send(ctx, newKeyChangeEvent(event)); err != nil {
+				return
+			}
+			*revision = event.Kv.ModRevision + 1
+		}
+	}
+	if ctx.Err() != nil {
+		return received, ctx.Err()
+	}
+	// 没有收到结束的响应时watchChan被关闭，说明Watcher或etcd连接已关闭
+	return received, ErrWatchClosed
+}
+
+func (w *WatchKeyChangeResponse) send(ctx context.Context, event *KeyChangeEvent) error {
+	select {
+	case w.Event <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func newKeyChangeEvent(event *clientv3.Event) *KeyChangeEvent {
+	changeEvent := &KeyChangeEvent{
+		Key:      string(event.Kv.Key),
+		Revision: event.Kv.ModRevision,
+	}
+
+	switch event.Type {
+	case mvccpb.PUT:
+		if event.IsCreate() {
+			changeEvent.Type = KeyCreateChangeEvent
+		} else {
+			changeEvent.Type = KeyUpdateChangeEvent
+		}
+		changeEvent.Value = event.Kv.Value
+	case mvccpb.DELETE:
+		changeEvent.Type = KeyDeleteChangeEvent
+	}
+	return changeEvent
+}
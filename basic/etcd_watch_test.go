@@ -0,0 +1,115 @@
+package basic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 需要etcd，通过MSNOWFLAKE_TEST_ETCD指定地址，未指定时跳过
+func testEtcd(t *testing.T) *Etcd {
+	endpoints := os.Getenv("MSNOWFLAKE_TEST_ETCD")
+	if len(endpoints) == 0 {
+		t.Skip("未设置MSNOWFLAKE_TEST_ETCD")
+	}
+	etcd, err := NewEtcd(EtcdConfig{
+		Endpoints:      strings.Split(endpoints, ","),
+		ConnectTimeout: 5 * time.Second,
+		ReadTimeout:    2 * time.Second,
+	})
+	if err != nil {
+		t.Fatal("初始化etcd失败", err)
+	}
+	return etcd
+}
+
+func nextEvent(t *testing.T, w *WatchKeyChangeResponse) *KeyChangeEvent {
+	select {
+	case event, ok := <-w.Event:
+		if !ok {
+			t.Fatal("watch提前结束", w.Err())
+		}
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("等待事件超时")
+	}
+	return nil
+}
+
+func TestEtcd_WatchWithContext(t *testing.T) {
+	etcd := testEtcd(t)
+	defer etcd.Close()
+	prefix := fmt.Sprintf("test/watch/%d/", time.Now().UnixNano())
+	defer func() { _ = etcd.DeleteWithPrefixKey(prefix) }()
+
+	// 从当前revision之后开始watch，避免watch建立前的事件被漏掉
+	resp, err := etcd.client.Get(context.Background(), prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := etcd.WatchWithContext(context.Background(), prefix, WatchPrefix(), WatchFromRevision(resp.Header.Revision+1))
+	_ = etcd.Put(prefix+"a", "1")
+	_ = etcd.Put(prefix+"a", "2")
+	_ = etcd.Delete(prefix + "a")
+	for _, expected := range []int{KeyCreateChangeEvent, KeyUpdateChangeEvent, KeyDeleteChangeEvent} {
+		if event := nextEvent(t, w); event.Type != expected || event.Key != prefix+"a" || event.Revision == 0 {
+			t.Fatalf("事件不正确: %+v", event)
+		}
+	}
+
+	w.CancelFunc()
+	for range w.Event {
+	}
+	if !errors.Is(w.Err(), context.Canceled) {
+		t.Fatal("取消后应该关闭Event", w.Err())
+	}
+}
+
+func TestEtcd_WatchCompacted(t *testing.T) {
+	etcd := testEtcd(t)
+	defer etcd.Close()
+	key := fmt.Sprintf("test/watch/%d", time.Now().UnixNano())
+	defer func() { _ = etcd.Delete(key) }()
+
+	var revisions []int64
+	for i := 0; i < 3; i++ {
+		resp, err := etcd.client.Put(context.Background(), key, fmt.Sprint(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		revisions = append(revisions, resp.Header.Revision)
+	}
+
+	// 从第二次put开始watch
+	w := etcd.WatchWithContext(context.Background(), key, WatchFromRevision(revisions[1]))
+	defer w.CancelFunc()
+	for i := 1; i < 3; i++ {
+		if event := nextEvent(t, w); event.Revision != revisions[i] || string(event.Value) != fmt.Sprint(i) {
+			t.Fatalf("事件不正确: %+v", event)
+		}
+	}
+
+	// revision被压缩后从压缩后的revision继续
+	if _, err := etcd.client.Compact(context.Background(), revisions[2]); err != nil {
+		t.Fatal(err)
+	}
+	compacted := etcd.WatchWithContext(context.Background(), key, WatchFromRevision(revisions[0]))
+	defer compacted.CancelFunc()
+	if event := nextEvent(t, compacted); event.Type != KeyCompactedChangeEvent || event.Revision != revisions[2] {
+		t.Fatalf("应该收到压缩事件: %+v", event)
+	}
+	if event := nextEvent(t, compacted); event.Revision != revisions[2] {
+		t.Fatalf("应该从压缩后的revision继续: %+v", event)
+	}
+	_ = etcd.Put(key, "3")
+	if event := nextEvent(t, compacted); string(event.Value) != "3" {
+		t.Fatalf("事件不正确: %+v", event)
+	}
+	if event := nextEvent(t, w); string(event.Value) != "3" {
+		t.Fatalf("事件不正确: %+v", event)
+	}
+}
@@ -0,0 +1,9 @@
+package basic
+
+type JournalConfig struct {
+	// 审计日志文件名，为空时不记录
+	Filename   string
+	MaxSize    int // megabytes
+	MaxAge     int // days
+	MaxBackups int
+}
@@ -0,0 +1,6 @@
+package basic
+
+type LimitConfig struct {
+	// 每项为"caller=rate:burst:dailyQuota"，caller为*时作为默认配置
+	Limits []string
+}
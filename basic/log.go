@@ -15,6 +15,12 @@ type LogConfig struct {
 	MaxSize  int
 }
 
+// 请求日志的采样: 每秒内前First条都记录，之后每Thereafter条记录一条，返回错误的请求不采样。First为0时只记录返回错误的请求
+type RequestLogConfig struct {
+	First      int
+	Thereafter int
+}
+
 func InitLog(config LogConfig) {
 	w := zapcore.AddSync(&lumberjack.Logger{
 		Filename:   config.Filename,
@@ -0,0 +1,212 @@
+package basic
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const otlpTracesPath = "/v1/traces"
+
+// OTLP/HTTP的JSON编码exporter。官方的otlp exporter依赖新版本grpc，与etcd v3.3的客户端不兼容，
+// 所以这里按照OTLP的JSON格式直接上报，collector默认的4318端口即可接收
+type otlpExporter struct {
+	url    string
+	client *http.Client
+}
+
+// endpoint没有指定路径时使用/v1/traces
+func NewOTLPExporter(endpoint string) (sdktrace.SpanExporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.New(fmt.Sprintf("trace的endpoint必须是http或https地址: %s", endpoint))
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = otlpTracesPath
+	}
+	return &otlpExporter{url: u.String(), client: &http.Client{Timeout: 10 * time.Second}}, nil
+}
+
+func (e *otlpExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(otlpRequestOf(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := e.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+		return errors.New(fmt.Sprintf("上报trace失败, status:%d, body:%s", res.StatusCode, msg))
+	}
+	_, _ = io.Copy(ioutil.Discard, res.Body)
+	return nil
+}
+
+func (e *otlpExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// 以下结构对应opentelemetry-proto中ExportTraceServiceRequest的JSON编码
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	SchemaUrl  string           `json:"schemaUrl,omitempty"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceId           string         `json:"traceId"`
+	SpanId            string         `json:"spanId"`
+	ParentSpanId      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Events            []otlpEvent    `json:"events,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string         `json:"timeUnixNano"`
+	Name         string         `json:"name"`
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+// OTLP的status code: 0 unset, 1 ok, 2 error
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"` // int64按JSON编码规则使用字符串
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+// 同一个TracerProvider的span共用一个resource，按instrumentation library分组
+func otlpRequestOf(spans []sdktrace.ReadOnlySpan) otlpRequest {
+	rs := otlpResourceSpans{}
+	if r := spans[0].Resource(); r != nil {
+		rs.Resource.Attributes = otlpAttributes(r.Attributes())
+		rs.SchemaUrl = r.SchemaURL()
+	}
+	scopes := make(map[string]int)
+	for _, span := range spans {
+		library := span.InstrumentationLibrary()
+		i, ok := scopes[library.Name+"@"+library.Version]
+		if !ok {
+			i = len(rs.ScopeSpans)
+			scopes[library.Name+"@"+library.Version] = i
+			rs.ScopeSpans = append(rs.ScopeSpans, otlpScopeSpans{Scope: otlpScope{Name: library.Name, Version: library.Version}})
+		}
+		rs.ScopeSpans[i].Spans = append(rs.ScopeSpans[i].Spans, otlpSpanOf(span))
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{rs}}
+}
+
+func otlpSpanOf(span sdktrace.ReadOnlySpan) otlpSpan {
+	sc := span.SpanContext()
+	traceId, spanId := sc.TraceID(), sc.SpanID()
+	s := otlpSpan{
+		TraceId:           hex.EncodeToString(traceId[:]),
+		SpanId:            hex.EncodeToString(spanId[:]),
+		Name:              span.Name(),
+		Kind:              int(span.SpanKind()), // trace.SpanKind的取值与OTLP一致
+		StartTimeUnixNano: strconv.FormatInt(span.StartTime().UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.EndTime().UnixNano(), 10),
+		Attributes:        otlpAttributes(span.Attributes()),
+	}
+	if parent := span.Parent(); parent.IsValid() {
+		parentId := parent.SpanID()
+		s.ParentSpanId = hex.EncodeToString(parentId[:])
+	}
+	for _, event := range span.Events() {
+		s.Events = append(s.Events, otlpEvent{
+			TimeUnixNano: strconv.FormatInt(event.Time.UnixNano(), 10),
+			Name:         event.Name,
+			Attributes:   otlpAttributes(event.Attributes),
+		})
+	}
+	switch status := span.Status(); status.Code {
+	case codes.Ok:
+		s.Status.Code = 1
+	case codes.Error:
+		s.Status.Code, s.Status.Message = 2, status.Description
+	}
+	return s
+}
+
+func otlpAttributes(attrs []attribute.KeyValue) []otlpKeyValue {
+	kvs := make([]otlpKeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		kv := otlpKeyValue{Key: string(attr.Key)}
+		switch attr.Value.Type() {
+		case attribute.BOOL:
+			v := attr.Value.AsBool()
+			kv.Value.BoolValue = &v
+		case attribute.INT64:
+			v := strconv.FormatInt(attr.Value.AsInt64(), 10)
+			kv.Value.IntValue = &v
+		case attribute.FLOAT64:
+			v := attr.Value.AsFloat64()
+			kv.Value.DoubleValue = &v
+		default:
+			// 数组等其他类型按字符串上报
+			v := attr.Value.Emit()
+			kv.Value.StringValue = &v
+		}
+		kvs = append(kvs, kv)
+	}
+	return kvs
+}
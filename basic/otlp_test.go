@@ -0,0 +1,84 @@
+package basic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOTLPExporter(t *testing.T) {
+	requests := make(chan otlpRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req otlpRequest
+		if r.URL.Path != otlpTracesPath || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("path:%s, content-type:%s", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error("解析上报的trace失败", err)
+		}
+		requests <- req
+	}))
+	defer server.Close()
+
+	exporter, err := NewOTLPExporter(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes("", attribute.String("service.name", "test"))),
+	)
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "parent", trace.WithSpanKind(trace.SpanKindServer))
+	_, child := provider.Tracer("test").Start(ctx, "child", trace.WithAttributes(attribute.Int64("num", 10)))
+	child.AddEvent("sequence_exhausted")
+	EndSpan(child, errors.New("失败"))
+
+	req := <-requests
+	if len(req.ResourceSpans) != 1 || len(req.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("上报的结构不正确: %+v", req)
+	}
+	if attrs := req.ResourceSpans[0].Resource.Attributes; len(attrs) != 1 || *attrs[0].Value.StringValue != "test" {
+		t.Fatalf("resource不正确: %+v", attrs)
+	}
+	span := req.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	parentId := parent.SpanContext().SpanID()
+	traceId := parent.SpanContext().TraceID()
+	if span.Name != "child" || span.ParentSpanId != parentId.String() || span.TraceId != traceId.String() {
+		t.Fatalf("span不正确: %+v", span)
+	}
+	if span.Kind != int(trace.SpanKindInternal) || span.Status.Code != 2 || span.Status.Message != "失败" {
+		t.Fatalf("span的kind或status不正确: %+v", span)
+	}
+	if len(span.Attributes) != 1 || *span.Attributes[0].Value.IntValue != "10" {
+		t.Fatalf("span的attributes不正确: %+v", span.Attributes)
+	}
+	// RecordError也会记录一个exception事件
+	if len(span.Events) != 2 || span.Events[0].Name != "sequence_exhausted" {
+		t.Fatalf("span的events不正确: %+v", span.Events)
+	}
+
+	parent.End()
+	if span = (<-requests).ResourceSpans[0].ScopeSpans[0].Spans[0]; span.ParentSpanId != "" || span.Kind != int(trace.SpanKindServer) {
+		t.Fatalf("span不正确: %+v", span)
+	}
+	_ = provider.Shutdown(context.Background())
+}
+
+func TestNewOTLPExporter(t *testing.T) {
+	for _, endpoint := range []string{"127.0.0.1:4318", "grpc://127.0.0.1:4317"} {
+		if _, err := NewOTLPExporter(endpoint); err == nil {
+			t.Errorf("非http地址应该返回错误: %s", endpoint)
+		}
+	}
+	exporter, err := NewOTLPExporter("https://collector/custom/traces")
+	if err != nil || exporter.(*otlpExporter).url != "https://collector/custom/traces" {
+		t.Fatal("指定路径时不应该修改", err)
+	}
+}
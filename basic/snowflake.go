@@ -1,15 +1,53 @@
 package basic
 
 import (
+	"errors"
 	"go.uber.org/zap"
 	"time"
 )
 
+// 默认的twepoch
+const DefaultTwepoch = "2020-02-02T13:14:52Z"
+
+// 默认向etcd公布节点信息的间隔
+const DefaultNodePublishInterval = 10 * time.Second
+
+// 自动分配workerId的方式
+const (
+	// 由选举出的leader统一分配，支持保留和冷却期
+	WorkerAssignmentLeader = "leader"
+	// 各节点在分布式锁的保护下自行选择空闲的workerId
+	WorkerAssignmentLease = "lease"
+)
+
+// 默认等待leader分配workerId的时间
+const DefaultWorkerAssignTimeout = 10 * time.Second
+
 type SnowflakeConfig struct {
 	Port       int64
 	WorkerId   int64
 	DataCenter int64
 	Twepoch    string
+	// Twepoch不带时区时使用的时区，如UTC、Asia/Shanghai
+	TwepochTimezone string
+	// timestamp耗尽前预留的时间，进入预留期后拒绝发号
+	SafetyMargin time.Duration
+	// id的位布局，预置布局名称或各字段位数
+	Layout string
+	// 每个时间单位的sequence起始方式: zero、random、rotating
+	SequenceStart string
+	// 混淆id的密钥，为空时不混淆
+	ObfuscateKey string
+	// 向etcd公布节点信息(包括已发出的timestamp区间)的间隔
+	NodePublishInterval time.Duration
+	// WorkerId小于0时自动分配workerId的方式: leader、lease
+	WorkerAssignment string
+	// leader分配时workerId释放后的冷却时间，冷却期内不会再分配给其他节点
+	WorkerCooldown time.Duration
+	// leader分配时保留给指定主机的workerId，格式为workerId=host
+	WorkerReservations []string
+	// 等待leader分配workerId的时间
+	WorkerAssignTimeout time.Duration
 }
 
 func (p SnowflakeConfig) GetPort() int64 {
@@ -20,8 +58,26 @@ func (p SnowflakeConfig) GetDataCenter() int64 {
 	return p.DataCenter
 }
 
+// twepoch必须显式带上时区，如2020-02-02T13:14:52Z、2020-02-02T21:14:52+08:00，
+// 也可以是"2006-01-02 15:04:05"格式并通过TwepochTimezone指定时区
 func (p SnowflakeConfig) GetTwepoch() (time.Time, error) {
-	twepoch, err := time.Parse("2006-01-02 15:04:05", p.Twepoch)
+	if twepoch, err := time.Parse(time.RFC3339Nano, p.Twepoch); err == nil {
+		return twepoch, nil
+	}
+
+	if len(p.TwepochTimezone) == 0 {
+		zap.S().Errorw("Snowflake的Twepoch没有指定时区",
+			"twepoch", p.Twepoch)
+		return time.Time{}, errors.New("twepoch必须使用RFC3339格式或者配置twepoch时区")
+	}
+	location, err := time.LoadLocation(p.TwepochTimezone)
+	if err != nil {
+		zap.S().Errorw("Snowflake的Twepoch时区配置不正确",
+			"timezone", p.TwepochTimezone,
+			"err", err)
+		return time.Time{}, err
+	}
+	twepoch, err := time.ParseInLocation("2006-01-02 15:04:05", p.Twepoch, location)
 	if err != nil {
 		zap.S().Errorw("Snowflake的Twepoch配置不正确",
 			"twepoch", p.Twepoch,
@@ -34,3 +90,48 @@ func (p SnowflakeConfig) GetTwepoch() (time.Time, error) {
 func (p SnowflakeConfig) GetWorkerId() int64 {
 	return p.WorkerId
 }
+
+func (p SnowflakeConfig) GetSafetyMargin() time.Duration {
+	return p.SafetyMargin
+}
+
+func (p SnowflakeConfig) GetLayout() string {
+	return p.Layout
+}
+
+func (p SnowflakeConfig) GetObfuscateKey() string {
+	return p.ObfuscateKey
+}
+
+func (p SnowflakeConfig) GetSequenceStart() string {
+	return p.SequenceStart
+}
+
+func (p SnowflakeConfig) GetNodePublishInterval() time.Duration {
+	if p.NodePublishInterval <= 0 {
+		return DefaultNodePublishInterval
+	}
+	return p.NodePublishInterval
+}
+
+func (p SnowflakeConfig) GetWorkerAssignment() string {
+	if len(p.WorkerAssignment) == 0 {
+		return WorkerAssignmentLeader
+	}
+	return p.WorkerAssignment
+}
+
+func (p SnowflakeConfig) GetWorkerCooldown() time.Duration {
+	return p.WorkerCooldown
+}
+
+func (p SnowflakeConfig) GetWorkerReservations() []string {
+	return p.WorkerReservations
+}
+
+func (p SnowflakeConfig) GetWorkerAssignTimeout() time.Duration {
+	if p.WorkerAssignTimeout <= 0 {
+		return DefaultWorkerAssignTimeout
+	}
+	return p.WorkerAssignTimeout
+}
@@ -0,0 +1,33 @@
+package basic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSnowflakeConfig_GetTwepoch(t *testing.T) {
+	want := time.Date(2020, 2, 2, 13, 14, 52, 0, time.UTC)
+
+	twepoch, err := SnowflakeConfig{Twepoch: DefaultTwepoch}.GetTwepoch()
+	if err != nil || !twepoch.Equal(want) {
+		t.Error("解析RFC3339格式的twepoch失败", twepoch, err)
+	}
+
+	twepoch, err = SnowflakeConfig{Twepoch: "2020-02-02T21:14:52+08:00"}.GetTwepoch()
+	if err != nil || !twepoch.Equal(want) {
+		t.Error("解析带时区偏移的twepoch失败", twepoch, err)
+	}
+
+	if _, err = (SnowflakeConfig{Twepoch: "2020-02-02 13:14:52"}).GetTwepoch(); err == nil {
+		t.Error("没有时区的twepoch应该返回错误")
+	}
+
+	twepoch, err = SnowflakeConfig{Twepoch: "2020-02-02 21:14:52", TwepochTimezone: "Asia/Shanghai"}.GetTwepoch()
+	if err != nil || !twepoch.Equal(want) {
+		t.Error("按指定时区解析twepoch失败", twepoch, err)
+	}
+
+	if _, err = (SnowflakeConfig{Twepoch: "2020-02-02 13:14:52", TwepochTimezone: "Mars/Olympus"}).GetTwepoch(); err == nil {
+		t.Error("时区不存在时应该返回错误")
+	}
+}
@@ -0,0 +1,72 @@
+package basic
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"io/ioutil"
+)
+
+// 证书配置，作为服务端时CAFile用于校验客户端证书(双向TLS)，作为客户端时用于校验服务端证书
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	CAFile   string
+}
+
+// 是否开启了TLS
+func (p TLSConfig) Enabled() bool {
+	return len(p.CertFile) > 0 || len(p.KeyFile) > 0 || len(p.CAFile) > 0
+}
+
+// 服务端的tls配置，配置了CAFile时要求客户端提供由该CA签发的证书
+func (p TLSConfig) ServerTLS() (*tls.Config, error) {
+	if len(p.CertFile) == 0 || len(p.KeyFile) == 0 {
+		return nil, errors.New("服务端开启TLS必须同时配置证书和私钥")
+	}
+	config, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+	if config.RootCAs != nil {
+		config.ClientCAs = config.RootCAs
+		config.RootCAs = nil
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// 客户端的tls配置，配置了证书时用于双向TLS
+func (p TLSConfig) ClientTLS() (*tls.Config, error) {
+	if (len(p.CertFile) == 0) != (len(p.KeyFile) == 0) {
+		return nil, errors.New("证书和私钥必须同时配置")
+	}
+	return p.load()
+}
+
+func (p TLSConfig) load() (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if len(p.CertFile) > 0 {
+		cert, err := tls.LoadX509KeyPair(p.CertFile, p.KeyFile)
+		if err != nil {
+			zap.S().Errorw("加载TLS证书失败", "cert", p.CertFile, "key", p.KeyFile, "err", err)
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if len(p.CAFile) > 0 {
+		pem, err := ioutil.ReadFile(p.CAFile)
+		if err != nil {
+			zap.S().Errorw("读取CA证书失败", "ca", p.CAFile, "err", err)
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New(fmt.Sprintf("CA证书格式不正确: %s", p.CAFile))
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
@@ -0,0 +1,85 @@
+package basic
+
+import (
+	"context"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"time"
+)
+
+var (
+	tracerProvider *sdktrace.TracerProvider
+	etcdTracer     = otel.Tracer("github.com/LazzyQ/msnowflake/basic")
+)
+
+type TraceConfig struct {
+	// OTLP/HTTP的地址，如http://127.0.0.1:4318，为空时不上报
+	Endpoint    string
+	ServiceName string
+	// 没有上游span时的采样比例，有上游span时跟随上游
+	SampleRatio float64
+}
+
+func (c TraceConfig) Enabled() bool {
+	return len(c.Endpoint) > 0
+}
+
+// 设置全局的TracerProvider和W3C trace context传播，未配置Endpoint时span都是空操作
+func InitTrace(config TraceConfig) error {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if !config.Enabled() {
+		return nil
+	}
+	exporter, err := NewOTLPExporter(config.Endpoint)
+	if err != nil {
+		zap.S().Errorw("trace的endpoint配置不正确", "endpoint", config.Endpoint, "err", err)
+		return err
+	}
+	tracerProvider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(config.ServiceName))),
+	)
+	otel.SetTracerProvider(tracerProvider)
+	zap.S().Infow("开启trace上报", "endpoint", config.Endpoint, "采样比例", config.SampleRatio)
+	return nil
+}
+
+// 上报缓存中的span并停止上报
+func ShutdownTrace() error {
+	if tracerProvider == nil {
+		return nil
+	}
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFunc()
+	return tracerProvider.Shutdown(ctx)
+}
+
+// 出错时把span标记为失败
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// 为etcd操作创建span，key为前缀时同样记录在etcd.key中。
+// 没有ctx参数的方法(Get、Put、TxKeepaliveWithTTL等)使用context.Background，创建的是没有上游的根span，
+// 需要关联到请求的trace时使用对应的WithContext方法
+func startEtcdSpan(ctx context.Context, operation, key string) (context.Context, trace.Span) {
+	return etcdTracer.Start(ctx, "etcd."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemKey.String("etcd"),
+			semconv.DBOperationKey.String(operation),
+			attribute.String("etcd.key", key),
+		))
+}
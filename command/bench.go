@@ -0,0 +1,171 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	msnowflake "github.com/LazzyQ/msnowflake/proto"
+	"github.com/micro/cli/v2"
+	"github.com/micro/go-micro/v2/client"
+	"sort"
+	"sync"
+	"time"
+)
+
+type benchResult struct {
+	requests  int
+	failures  int
+	lastErr   error
+	ids       []int64
+	latencies []time.Duration
+}
+
+func benchCommand(service func() msnowflake.MSnowflakeService) *cli.Command {
+	return &cli.Command{
+		Name:  "bench",
+		Usage: "压测正在运行的msnowflake服务",
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:  "concurrency",
+				Usage: "并发数",
+				Value: 10,
+			},
+			&cli.UintFlag{
+				Name:  "batch",
+				Usage: "每次请求获取的id数量，为1时调用NextId，否则调用NextIds",
+				Value: 1,
+			},
+			&cli.DurationFlag{
+				Name:  "duration",
+				Usage: "压测持续时间",
+				Value: 10 * time.Second,
+			},
+			&cli.DurationFlag{
+				Name:  "request_timeout",
+				Usage: "单次请求超时时间",
+				Value: time.Second,
+			},
+			callerFlag,
+		},
+		Action: func(c *cli.Context) error {
+			concurrency := c.Int("concurrency")
+			batch := uint32(c.Uint("batch"))
+			if concurrency <= 0 || batch == 0 {
+				return errors.New("concurrency和batch必须大于0")
+			}
+			return bench(service(), concurrency, batch, c.Duration("duration"), c.Duration("request_timeout"), c.String("caller"))
+		},
+	}
+}
+
+func bench(service msnowflake.MSnowflakeService, concurrency int, batch uint32, duration, timeout time.Duration, caller string) error {
+	var (
+		wg      sync.WaitGroup
+		results = make([]*benchResult, concurrency)
+	)
+	fmt.Printf("开始压测, 并发数:%d, batch:%d, 持续时间:%v\n", concurrency, batch, duration)
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), duration)
+	defer cancelFunc()
+	start := time.Now()
+	for i := 0; i < concurrency; i++ {
+		results[i] = &benchResult{}
+		wg.Add(1)
+		go func(result *benchResult) {
+			defer wg.Done()
+			benchWorker(ctx, service, batch, timeout, caller, result)
+		}(results[i])
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	if duplicates := report(results, elapsed); duplicates > 0 {
+		return errors.New(fmt.Sprintf("发现%d个重复id", duplicates))
+	}
+	return nil
+}
+
+func benchWorker(ctx context.Context, service msnowflake.MSnowflakeService, batch uint32, timeout time.Duration, caller string, result *benchResult) {
+	var (
+		res *msnowflake.IdResponse
+		err error
+	)
+	req := &msnowflake.IdRequest{Num: batch, Caller: caller}
+	for ctx.Err() == nil {
+		begin := time.Now()
+		if batch == 1 {
+			res, err = service.NextId(context.Background(), req, client.WithRequestTimeout(timeout))
+		} else {
+			res, err = service.NextIds(context.Background(), req, client.WithRequestTimeout(timeout))
+		}
+		result.latencies = append(result.latencies, time.Since(begin))
+		result.requests++
+		if err == nil && res.Code != 0 {
+			err = errors.New(res.Message)
+		}
+		if err != nil {
+			result.failures++
+			result.lastErr = err
+			continue
+		}
+		if batch == 1 {
+			result.ids = append(result.ids, res.Id)
+		} else {
+			result.ids = append(result.ids, res.Ids...)
+		}
+	}
+}
+
+func report(results []*benchResult, elapsed time.Duration) (duplicates int) {
+	var (
+		requests, failures int
+		lastErr            error
+		latencies          []time.Duration
+		ids                = make(map[int64]struct{})
+	)
+	for _, result := range results {
+		requests += result.requests
+		failures += result.failures
+		if result.lastErr != nil {
+			lastErr = result.lastErr
+		}
+		latencies = append(latencies, result.latencies...)
+		for _, id := range result.ids {
+			if _, ok := ids[id]; ok {
+				duplicates++
+				continue
+			}
+			ids[id] = struct{}{}
+		}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	seconds := elapsed.Seconds()
+	fmt.Printf("耗时: %v\n", elapsed.Round(time.Millisecond))
+	fmt.Printf("请求数: %d, 失败: %d, 吞吐: %.1f req/s\n", requests, failures, float64(requests)/seconds)
+	fmt.Printf("id数: %d, 重复: %d, 吞吐: %.1f id/s\n", len(ids)+duplicates, duplicates, float64(len(ids)+duplicates)/seconds)
+	if lastErr != nil {
+		fmt.Printf("最近一次失败: %v\n", lastErr)
+	}
+	if len(latencies) > 0 {
+		fmt.Printf("延迟: p50=%v p90=%v p99=%v p999=%v max=%v\n",
+			percentile(latencies, 0.5),
+			percentile(latencies, 0.9),
+			percentile(latencies, 0.99),
+			percentile(latencies, 0.999),
+			latencies[len(latencies)-1])
+	}
+	return
+}
+
+// latencies必须已经排好序
+func percentile(latencies []time.Duration, p float64) time.Duration {
+	i := int(float64(len(latencies))*p+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(latencies) {
+		i = len(latencies) - 1
+	}
+	return latencies[i]
+}
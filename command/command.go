@@ -0,0 +1,81 @@
+package command
+
+import (
+	"context"
+	"github.com/LazzyQ/msnowflake/basic"
+	msnowflake "github.com/LazzyQ/msnowflake/proto"
+	"github.com/micro/cli/v2"
+	"github.com/micro/go-micro/v2"
+	"github.com/micro/go-micro/v2/client"
+	gcli "github.com/micro/go-micro/v2/client/grpc"
+	"github.com/micro/go-micro/v2/metadata"
+)
+
+var (
+	executed bool
+)
+
+// 注册子命令，子命令通过服务的client调用正在运行的msnowflake，
+// tlsConfig在解析完命令行参数后用于配置client，token使用go-micro的auth_token参数
+func Commands(serviceName string, tlsConfig *basic.TLSConfig) micro.Option {
+	return func(o *micro.Options) {
+		// client在解析完命令行参数后才确定，必须在子命令执行时再获取
+		service := func() msnowflake.MSnowflakeService {
+			return msnowflake.NewMSnowflakeService(serviceName, o.Client)
+		}
+		app := o.Cmd.App()
+		app.Commands = append(app.Commands,
+			benchCommand(service),
+			nextCommand(service),
+			parseCommand(),
+			journalCommand(),
+			infoCommand(service),
+			rangeCommand(service),
+			statsCommand(service),
+		)
+
+		before := app.Before
+		app.Before = func(c *cli.Context) error {
+			if c.Args().Present() && app.Command(c.Args().First()) != nil {
+				executed = true
+			}
+			if before != nil {
+				if err := before(c); err != nil {
+					return err
+				}
+			}
+			if !executed {
+				return nil
+			}
+			if tlsConfig.Enabled() {
+				config, err := tlsConfig.ClientTLS()
+				if err != nil {
+					return err
+				}
+				if err = o.Client.Init(gcli.AuthTLS(config)); err != nil {
+					return err
+				}
+			}
+			if token := c.String("auth_token"); len(token) > 0 {
+				o.Client = tokenClient{Client: o.Client, token: token}
+			}
+			return nil
+		}
+	}
+}
+
+// 调用时在metadata中携带token
+type tokenClient struct {
+	client.Client
+	token string
+}
+
+func (c tokenClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	ctx = metadata.MergeContext(ctx, metadata.Metadata{"Authorization": "Bearer " + c.token}, true)
+	return c.Client.Call(ctx, req, rsp, opts...)
+}
+
+// 是否执行了子命令，执行了子命令就不再启动服务
+func Executed() bool {
+	return executed
+}
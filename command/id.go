@@ -0,0 +1,366 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/LazzyQ/msnowflake/basic"
+	"github.com/LazzyQ/msnowflake/model"
+	msnowflake "github.com/LazzyQ/msnowflake/proto"
+	"github.com/micro/cli/v2"
+	"os"
+	"strconv"
+	"time"
+)
+
+var (
+	formatFlag = &cli.StringFlag{
+		Name:  "format",
+		Usage: "输出格式: text或json",
+		Value: "text",
+	}
+	encodingFlag = &cli.StringFlag{
+		Name:  "encoding",
+		Usage: "id的字符串编码: none、base62、crockford_base32、hex、decimal",
+		Value: model.EncodingNone.String(),
+	}
+	callerFlag = &cli.StringFlag{
+		Name:  "caller",
+		Usage: "调用方标识，服务端按调用方限流",
+	}
+)
+
+func nextCommand(service func() msnowflake.MSnowflakeService) *cli.Command {
+	return &cli.Command{
+		Name:  "next",
+		Usage: "从正在运行的msnowflake服务获取id",
+		Flags: []cli.Flag{
+			&cli.UintFlag{
+				Name:  "num",
+				Usage: "获取的id数量",
+				Value: 1,
+			},
+			&cli.StringFlag{
+				Name:  "type",
+				Usage: "id类型: snowflake、ulid、uuid",
+				Value: "snowflake",
+			},
+			encodingFlag,
+			callerFlag,
+			formatFlag,
+		},
+		Action: func(c *cli.Context) error {
+			var (
+				res *msnowflake.IdResponse
+			)
+			num := uint32(c.Uint("num"))
+			switch c.String("type") {
+			case "snowflake":
+			case "ulid", "uuid":
+				return nextBytesIds(c, service(), num)
+			default:
+				return errors.New(fmt.Sprintf("不支持的id类型: %s", c.String("type")))
+			}
+
+			encoding, err := model.ParseEncoding(c.String("encoding"))
+			if err != nil {
+				return err
+			}
+			req := &msnowflake.IdRequest{Num: num, Encoding: msnowflake.Encoding(encoding), Caller: c.String("caller")}
+			if num == 1 {
+				res, err = service().NextId(context.Background(), req)
+			} else {
+				res, err = service().NextIds(context.Background(), req)
+			}
+			if err != nil {
+				return err
+			}
+			if res.Code != 0 {
+				return errors.New(fmt.Sprintf("获取id失败, code:%d, message:%s, requestId:%s", res.Code, res.Message, res.RequestId))
+			}
+
+			ids, strs := res.Ids, res.IdsStr
+			if num == 1 {
+				ids, strs = []int64{res.Id}, []string{res.IdStr}
+			}
+			if c.String("format") == "json" {
+				if encoding == model.EncodingNone {
+					return printJSON(map[string][]int64{"ids": ids})
+				}
+				return printJSON(map[string]interface{}{"ids": ids, "ids_str": strs})
+			}
+			for i, id := range ids {
+				if encoding == model.EncodingNone {
+					fmt.Println(id)
+				} else {
+					fmt.Println(id, strs[i])
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func nextBytesIds(c *cli.Context, service msnowflake.MSnowflakeService, num uint32) (err error) {
+	var res *msnowflake.BytesIdResponse
+	req := &msnowflake.IdRequest{Num: num, Caller: c.String("caller")}
+	if c.String("type") == "ulid" {
+		res, err = service.NextUlids(context.Background(), req)
+	} else {
+		res, err = service.NextUuids(context.Background(), req)
+	}
+	if err != nil {
+		return err
+	}
+	if res.Code != 0 {
+		return errors.New(fmt.Sprintf("获取id失败, code:%d, message:%s, requestId:%s", res.Code, res.Message, res.RequestId))
+	}
+
+	if c.String("format") == "json" {
+		return printJSON(map[string][]string{"ids": res.IdsStr})
+	}
+	for _, s := range res.IdsStr {
+		fmt.Println(s)
+	}
+	return nil
+}
+
+func parseCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "parse",
+		Usage:     "离线解析id的时间、dataCenterId、workerId和sequence",
+		ArgsUsage: "<id> [id...]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "twepoch",
+				Usage: "生成id时使用的twepoch，为空时使用layout默认的twepoch",
+			},
+			&cli.StringFlag{
+				Name:  "twepoch_timezone",
+				Usage: "twepoch为\"2006-01-02 15:04:05\"格式时使用的时区",
+			},
+			&cli.StringFlag{
+				Name:  "layout",
+				Usage: "id的位布局: twitter、sonyflake、instagram、discord，或timestamp,dataCenter,worker,sequence各字段位数",
+				Value: model.DefaultLayout.String(),
+			},
+			&cli.StringFlag{
+				Name:  "encoding",
+				Usage: "输入id的字符串编码: none、base62、crockford_base32、hex、decimal",
+				Value: model.EncodingNone.String(),
+			},
+			&cli.StringFlag{
+				Name:    "obfuscate_key",
+				Usage:   "服务端开启混淆时使用的密钥",
+				EnvVars: []string{"MSNOWFLAKE_OBFUSCATE_KEY"},
+			},
+			formatFlag,
+		},
+		Action: func(c *cli.Context) error {
+			if !c.Args().Present() {
+				return errors.New("缺少要解析的id")
+			}
+			layout, err := model.ParseLayout(c.String("layout"))
+			if err != nil {
+				return err
+			}
+			config := basic.SnowflakeConfig{
+				Twepoch:         c.String("twepoch"),
+				TwepochTimezone: c.String("twepoch_timezone"),
+			}
+			if len(config.Twepoch) == 0 {
+				config.Twepoch = layout.Twepoch
+			}
+			twepoch, err := config.GetTwepoch()
+			if err != nil {
+				return err
+			}
+
+			encoding, err := model.ParseEncoding(c.String("encoding"))
+			if err != nil {
+				return err
+			}
+
+			var obfuscator *model.Obfuscator
+			if key := c.String("obfuscate_key"); len(key) > 0 {
+				if obfuscator, err = model.NewObfuscator([]byte(key)); err != nil {
+					return err
+				}
+			}
+
+			infos := make([]model.IdInfo, 0, c.Args().Len())
+			for _, arg := range c.Args().Slice() {
+				id, err := parseId(arg, encoding)
+				if err != nil {
+					return err
+				}
+				if obfuscator != nil {
+					if id, err = obfuscator.Reveal(id); err != nil {
+						return err
+					}
+				}
+				infos = append(infos, model.Parse(id, twepoch, layout))
+			}
+
+			if c.String("format") == "json" {
+				return printJSON(infos)
+			}
+			for _, info := range infos {
+				fmt.Printf("id:%d time:%s dataCenterId:%d workerId:%d sequence:%d\n",
+					info.Id, info.Time.Format(time.RFC3339Nano), info.DataCenterId, info.WorkerId, info.Sequence)
+			}
+			return nil
+		},
+	}
+}
+
+func parseId(s string, encoding model.Encoding) (int64, error) {
+	if encoding != model.EncodingNone {
+		return model.Decode(s, encoding)
+	}
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("id格式不正确: %s", s))
+	}
+	return id, nil
+}
+
+func infoCommand(service func() msnowflake.MSnowflakeService) *cli.Command {
+	return &cli.Command{
+		Name:  "info",
+		Usage: "查看正在运行的msnowflake服务的布局、twepoch和timestamp耗尽时间",
+		Flags: []cli.Flag{
+			formatFlag,
+		},
+		Action: func(c *cli.Context) error {
+			res, err := service().Info(context.Background(), &msnowflake.InfoRequest{})
+			if err != nil {
+				return err
+			}
+			if res.Code != 0 {
+				return errors.New(fmt.Sprintf("获取信息失败, code:%d, message:%s", res.Code, res.Message))
+			}
+			if c.String("format") == "json" {
+				return printJSON(res)
+			}
+			fmt.Printf("layout: %s (timestamp:%d dataCenter:%d worker:%d sequence:%d, 时间单位:%dms)\n",
+				res.Layout, res.TimestampBits, res.DatacenterBits, res.WorkerBits, res.SequenceBits, res.TimeUnitMs)
+			fmt.Printf("dataCenterId: %d, workerId: %d, 混淆: %v\n", res.DatacenterId, res.WorkerId, res.Obfuscated)
+			fmt.Printf("twepoch: %s\n", formatMillis(res.Twepoch))
+			fmt.Printf("timestamp耗尽时间: %s\n", formatMillis(res.ExhaustTime))
+			fmt.Printf("停止发号时间: %s\n", formatMillis(res.ServeUntil))
+			return nil
+		},
+	}
+}
+
+func rangeCommand(service func() msnowflake.MSnowflakeService) *cli.Command {
+	return &cli.Command{
+		Name:        "range",
+		Usage:       "计算正在运行的msnowflake服务在时间范围内可能生成的最小和最大id",
+		ArgsUsage:   "<start> <end>",
+		Description: "start和end为RFC3339格式的时间，如2020-03-01T00:00:00+08:00，两端都包含在内",
+		Flags: []cli.Flag{
+			&cli.Int64Flag{
+				Name:  "datacenter_id",
+				Usage: "只计算指定dataCenterId的id，小于0时不限定",
+				Value: model.AnyId,
+			},
+			&cli.Int64Flag{
+				Name:  "worker_id",
+				Usage: "只计算指定workerId的id，小于0时不限定",
+				Value: model.AnyId,
+			},
+			&cli.StringFlag{
+				Name:  "encoding",
+				Usage: "同时返回字符串形式的id: none、base62、crockford_base32、hex、decimal",
+				Value: model.EncodingNone.String(),
+			},
+			formatFlag,
+		},
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() != 2 {
+				return errors.New("需要指定开始时间和结束时间")
+			}
+			start, err := time.Parse(time.RFC3339Nano, c.Args().Get(0))
+			if err != nil {
+				return errors.New(fmt.Sprintf("开始时间格式不正确: %s", c.Args().Get(0)))
+			}
+			end, err := time.Parse(time.RFC3339Nano, c.Args().Get(1))
+			if err != nil {
+				return errors.New(fmt.Sprintf("结束时间格式不正确: %s", c.Args().Get(1)))
+			}
+			encoding, err := model.ParseEncoding(c.String("encoding"))
+			if err != nil {
+				return err
+			}
+
+			req := &msnowflake.RangeRequest{
+				StartTime: start.UnixNano() / int64(time.Millisecond),
+				EndTime:   end.UnixNano() / int64(time.Millisecond),
+				Encoding:  msnowflake.Encoding(encoding),
+			}
+			if dataCenterId := c.Int64("datacenter_id"); dataCenterId >= 0 {
+				req.HasDatacenterId, req.DatacenterId = true, dataCenterId
+			}
+			if workerId := c.Int64("worker_id"); workerId >= 0 {
+				req.HasWorkerId, req.WorkerId = true, workerId
+			}
+			res, err := service().Range(context.Background(), req)
+			if err != nil {
+				return err
+			}
+			if res.Code != 0 {
+				return errors.New(fmt.Sprintf("计算id范围失败, code:%d, message:%s", res.Code, res.Message))
+			}
+
+			if c.String("format") == "json" {
+				return printJSON(res)
+			}
+			if encoding != model.EncodingNone {
+				fmt.Printf("min: %d (%s)\nmax: %d (%s)\n", res.MinId, res.MinIdStr, res.MaxId, res.MaxIdStr)
+				return nil
+			}
+			fmt.Printf("min: %d\nmax: %d\n", res.MinId, res.MaxId)
+			return nil
+		},
+	}
+}
+
+func statsCommand(service func() msnowflake.MSnowflakeService) *cli.Command {
+	return &cli.Command{
+		Name:  "stats",
+		Usage: "查看正在运行的msnowflake服务各调用方的发号和限流统计",
+		Flags: []cli.Flag{
+			formatFlag,
+		},
+		Action: func(c *cli.Context) error {
+			res, err := service().Stats(context.Background(), &msnowflake.StatsRequest{})
+			if err != nil {
+				return err
+			}
+			if res.Code != 0 {
+				return errors.New(fmt.Sprintf("获取统计失败, code:%d, message:%s", res.Code, res.Message))
+			}
+			if c.String("format") == "json" {
+				return printJSON(res.Callers)
+			}
+			for _, s := range res.Callers {
+				fmt.Printf("caller:%s issued:%d rateLimited:%d quotaExceeded:%d quota:%d/%d rate:%g burst:%d\n",
+					s.Caller, s.Issued, s.RateLimited, s.QuotaExceeded, s.QuotaUsed, s.DailyQuota, s.Rate, s.Burst)
+			}
+			return nil
+		},
+	}
+}
+
+func formatMillis(ms int64) string {
+	return time.Unix(0, ms*int64(time.Millisecond)).UTC().Format(time.RFC3339Nano)
+}
+
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
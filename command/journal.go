@@ -0,0 +1,123 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"github.com/LazzyQ/msnowflake/model"
+	"github.com/micro/cli/v2"
+	"os"
+	"time"
+)
+
+type journalMatch struct {
+	Id     int64               `json:"id"`
+	File   string              `json:"file"`
+	Record model.JournalRecord `json:"record"`
+}
+
+func journalCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "journal",
+		Usage:     "在审计日志中查找获得id的请求",
+		ArgsUsage: "<id> [id...]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "file",
+				Usage:    "审计日志文件名，与服务端的msnowflake_journal_filename一致，会同时查找轮转出的备份文件",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "layout",
+				Usage: "id的位布局: twitter、sonyflake、instagram、discord，或timestamp,dataCenter,worker,sequence各字段位数",
+				Value: model.DefaultLayout.String(),
+			},
+			encodingFlag,
+			&cli.StringFlag{
+				Name:    "obfuscate_key",
+				Usage:   "服务端开启混淆时使用的密钥",
+				EnvVars: []string{"MSNOWFLAKE_OBFUSCATE_KEY"},
+			},
+			formatFlag,
+		},
+		Action: func(c *cli.Context) error {
+			if !c.Args().Present() {
+				return errors.New("缺少要查找的id")
+			}
+			layout, err := model.ParseLayout(c.String("layout"))
+			if err != nil {
+				return err
+			}
+			encoding, err := model.ParseEncoding(c.String("encoding"))
+			if err != nil {
+				return err
+			}
+			var obfuscator *model.Obfuscator
+			if key := c.String("obfuscate_key"); len(key) > 0 {
+				if obfuscator, err = model.NewObfuscator([]byte(key)); err != nil {
+					return err
+				}
+			}
+
+			// 只比较timestamp、dataCenterId、workerId和sequence字段，不需要twepoch
+			infos := make([]model.IdInfo, 0, c.Args().Len())
+			for _, arg := range c.Args().Slice() {
+				id, err := parseId(arg, encoding)
+				if err != nil {
+					return err
+				}
+				raw := id
+				if obfuscator != nil {
+					if raw, err = obfuscator.Reveal(id); err != nil {
+						return err
+					}
+				}
+				info := model.Parse(raw, time.Unix(0, 0), layout)
+				info.Id = id
+				infos = append(infos, info)
+			}
+
+			files, err := model.JournalFiles(c.String("file"))
+			if err != nil {
+				return err
+			}
+			if len(files) == 0 {
+				return errors.New(fmt.Sprintf("审计日志不存在: %s", c.String("file")))
+			}
+			var matches []journalMatch
+			for _, file := range files {
+				if err = searchJournalFile(file, layout, infos, &matches); err != nil {
+					return err
+				}
+			}
+
+			if c.String("format") == "json" {
+				return printJSON(matches)
+			}
+			for _, m := range matches {
+				r := m.Record
+				fmt.Printf("id:%d caller:%s requestId:%s time:%s dataCenterId:%d workerId:%d 区间:[%d.%d, %d.%d] 数量:%d 文件:%s\n",
+					m.Id, r.Caller, r.RequestId, r.Time.Format(time.RFC3339Nano), r.DataCenterId, r.WorkerId,
+					r.TimestampFrom, r.SequenceFrom, r.TimestampTo, r.SequenceTo, r.Count, m.File)
+			}
+			if len(matches) == 0 {
+				return errors.New("没有找到包含id的记录")
+			}
+			return nil
+		},
+	}
+}
+
+func searchJournalFile(file string, layout model.Layout, infos []model.IdInfo, matches *[]journalMatch) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	skipped, err := model.SearchJournal(f, layout, infos, func(info model.IdInfo, record model.JournalRecord) {
+		*matches = append(*matches, journalMatch{Id: info.Id, File: file, Record: record})
+	})
+	if skipped > 0 {
+		fmt.Fprintf(os.Stderr, "%s中有%d行无法解析，已跳过\n", file, skipped)
+	}
+	return err
+}
@@ -0,0 +1,100 @@
+// 嵌入式发号器，在服务进程内直接生成id。
+// workerId通过etcd在命名空间(默认为msnowflake/)的worker/下注册，与使用相同命名空间的msnowflake服务节点互不重复，
+// 同时和服务节点一样在node/下公布节点信息，检测到同一个命名空间中绕过注册的冲突时停止发号
+package generator
+
+import (
+	"context"
+	"errors"
+	"github.com/LazzyQ/msnowflake/basic"
+	"github.com/LazzyQ/msnowflake/model"
+	"sync"
+)
+
+// 不指定workerId，从空闲的workerId中自动选择
+const AutoWorkerId = -1
+
+type Generator struct {
+	worker       *model.IdWorker
+	registration *model.Registration
+	detector     *model.Detector
+	etcd         *basic.Etcd
+	ownEtcd      bool // etcd由Generator创建，关闭时一起关闭
+	closeOnce    sync.Once
+}
+
+// 连接etcd并注册workerId，config.WorkerId为AutoWorkerId时自动选择
+func New(etcdConfig basic.EtcdConfig, config basic.SnowflakeConfig, opts ...model.Option) (*Generator, error) {
+	etcd, err := basic.NewEtcd(etcdConfig)
+	if err != nil {
+		return nil, err
+	}
+	g, err := NewWithEtcd(etcd, config, opts...)
+	if err != nil {
+		etcd.Close()
+		return nil, err
+	}
+	g.ownEtcd = true
+	return g, nil
+}
+
+// 使用已有的etcd连接注册workerId，Close时不关闭etcd
+func NewWithEtcd(etcd *basic.Etcd, config basic.SnowflakeConfig, opts ...model.Option) (*Generator, error) {
+	if etcd == nil {
+		return nil, errors.New("etcd不能为空")
+	}
+	worker, registration, err := model.Register(etcd, config, opts...)
+	if err != nil {
+		return nil, err
+	}
+	detector, err := model.StartDetector(etcd, worker, config.GetNodePublishInterval())
+	if err != nil {
+		_ = registration.Close()
+		return nil, err
+	}
+	return &Generator{
+		worker:       worker,
+		registration: registration,
+		detector:     detector,
+		etcd:         etcd,
+	}, nil
+}
+
+func (g *Generator) NextId(ctx context.Context) (int64, error) {
+	return g.worker.NextIdWithContext(ctx)
+}
+
+func (g *Generator) NextIds(ctx context.Context, num uint32) ([]int64, error) {
+	return g.worker.NextIdsWithContext(ctx, num)
+}
+
+func (g *Generator) NextUlids(ctx context.Context, num uint32) ([]model.Ulid, error) {
+	return g.worker.NextUlidsWithContext(ctx, num)
+}
+
+func (g *Generator) NextUuids(ctx context.Context, num uint32) ([]model.Uuid, error) {
+	return g.worker.NextUuidsWithContext(ctx, num)
+}
+
+// 注册的workerId等信息
+func (g *Generator) Info() model.WorkerInfo {
+	return g.worker.Info()
+}
+
+// 注册失效后关闭，此时停止发号，重新注册同一个workerId成功后恢复发号
+func (g *Generator) Done() <-chan struct{} {
+	return g.registration.Done
+}
+
+// 停止发号并释放workerId
+func (g *Generator) Close() (err error) {
+	g.closeOnce.Do(func() {
+		g.worker.Disable(errors.New("generator已关闭"))
+		g.detector.Close()
+		err = g.registration.Close()
+		if g.ownEtcd {
+			g.etcd.Close()
+		}
+	})
+	return err
+}
@@ -0,0 +1,256 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/LazzyQ/msnowflake/basic"
+	"github.com/LazzyQ/msnowflake/model"
+	"github.com/coreos/etcd/clientv3"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 需要etcd，通过MSNOWFLAKE_TEST_ETCD指定地址，未指定时跳过
+func testEtcdConfig(t *testing.T) basic.EtcdConfig {
+	endpoints := os.Getenv("MSNOWFLAKE_TEST_ETCD")
+	if len(endpoints) == 0 {
+		t.Skip("未设置MSNOWFLAKE_TEST_ETCD")
+	}
+	return basic.EtcdConfig{
+		Endpoints:      strings.Split(endpoints, ","),
+		ConnectTimeout: 5 * time.Second,
+		ReadTimeout:    2 * time.Second,
+	}
+}
+
+func TestGenerator_AutoWorkerId(t *testing.T) {
+	etcdConfig := testEtcdConfig(t)
+	config := basic.SnowflakeConfig{DataCenter: 1, WorkerId: AutoWorkerId}
+
+	first, err := New(etcdConfig, config)
+	if err != nil {
+		t.Fatal("创建generator失败", err)
+	}
+	defer first.Close()
+	second, err := New(etcdConfig, config)
+	if err != nil {
+		t.Fatal("创建generator失败", err)
+	}
+
+	if first.Info().WorkerId == second.Info().WorkerId {
+		t.Fatalf("自动分配的workerId重复: %d", first.Info().WorkerId)
+	}
+	// 指定已被占用的workerId时注册失败
+	if _, err := New(etcdConfig, basic.SnowflakeConfig{DataCenter: 1, WorkerId: first.Info().WorkerId}); err == nil {
+		t.Fatal("workerId已被占用时应该注册失败")
+	}
+
+	ids, err := second.NextIds(context.Background(), 10)
+	if err != nil || len(ids) != 10 {
+		t.Fatal("获取id失败", err)
+	}
+
+	// 关闭后释放workerId并停止发号
+	workerId := second.Info().WorkerId
+	if err := second.Close(); err != nil {
+		t.Fatal("关闭generator失败", err)
+	}
+	if _, err := second.NextId(context.Background()); err == nil {
+		t.Fatal("关闭后应该停止发号")
+	}
+	third, err := New(etcdConfig, basic.SnowflakeConfig{DataCenter: 1, WorkerId: workerId})
+	if err != nil {
+		t.Fatal("workerId释放后应该可以注册", err)
+	}
+	third.Close()
+}
+
+func TestGenerator_Reregister(t *testing.T) {
+	etcdConfig := testEtcdConfig(t)
+	g, err := New(etcdConfig, basic.SnowflakeConfig{
+		DataCenter:       1,
+		WorkerId:         AutoWorkerId,
+		WorkerAssignment: basic.WorkerAssignmentLease,
+	})
+	if err != nil {
+		t.Fatal("创建generator失败", err)
+	}
+	defer g.Close()
+
+	// 撤销worker的租约，模拟与etcd断开超过租约时间
+	client, err := clientv3.New(clientv3.Config{Endpoints: etcdConfig.Endpoints, DialTimeout: etcdConfig.ConnectTimeout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	key := basic.DefaultEtcdNamespace + "worker/" + strconv.FormatInt(g.Info().WorkerId, 10)
+	resp, err := client.Get(context.Background(), key)
+	if err != nil || len(resp.Kvs) == 0 {
+		t.Fatal("读取worker注册失败", err)
+	}
+	if _, err = client.Revoke(context.Background(), clientv3.LeaseID(resp.Kvs[0].Lease)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-g.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("租约撤销后Done应该被关闭")
+	}
+
+	// 重新注册同一个workerId后恢复发号
+	for deadline := time.Now().Add(3 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if resp, err = client.Get(context.Background(), key); err == nil && len(resp.Kvs) == 1 {
+			if _, err = g.NextId(context.Background()); err == nil {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("应该重新注册同一个workerId并恢复发号", err)
+		}
+	}
+
+	// 关闭后不再重新注册
+	if err = g.Close(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(500 * time.Millisecond)
+	if resp, err = client.Get(context.Background(), key); err != nil || len(resp.Kvs) != 0 {
+		t.Fatal("关闭后不应该重新注册", err)
+	}
+}
+
+func TestGenerator_LeaderAssignment(t *testing.T) {
+	etcdConfig := testEtcdConfig(t)
+	host, _ := os.Hostname()
+	config := basic.SnowflakeConfig{
+		DataCenter:         1,
+		WorkerId:           AutoWorkerId,
+		WorkerAssignment:   basic.WorkerAssignmentLeader,
+		WorkerReservations: []string{"9=" + host, "10=other-host"},
+		WorkerCooldown:     3 * time.Second,
+	}
+	start := time.Now()
+	// 清除之前的测试留下的冷却期
+	etcd, err := basic.NewEtcd(etcdConfig)
+	if err != nil {
+		t.Fatal("连接etcd失败", err)
+	}
+	defer etcd.Close()
+	if err = etcd.DeleteWithPrefixKey("assign/cooldown/"); err != nil {
+		t.Fatal(err)
+	}
+	// 超过保留时间的分配记录在下次分配时删除
+	expired := "assign/history/0000000000000000001-expired"
+	if err = etcd.Put(expired, "{}"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 第一个节点成为leader，分配保留给本主机的workerId
+	first, err := New(etcdConfig, config)
+	if err != nil {
+		t.Fatal("创建generator失败", err)
+	}
+	defer first.Close()
+	if first.Info().WorkerId != 9 {
+		t.Fatalf("应该分配保留的workerId: %d", first.Info().WorkerId)
+	}
+	second, err := New(etcdConfig, config)
+	if err != nil {
+		t.Fatal("创建generator失败", err)
+	}
+	defer second.Close()
+	released := second.Info().WorkerId
+	if released == 9 || released == 10 {
+		t.Fatalf("不应该分配保留的workerId: %d", released)
+	}
+
+	// 释放的workerId在冷却期内不会再分配，不需要等待leader收到释放事件
+	_ = second.Close()
+	third, err := New(etcdConfig, config)
+	if err != nil {
+		t.Fatal("创建generator失败", err)
+	}
+	defer third.Close()
+	if third.Info().WorkerId == released {
+		t.Fatalf("冷却期内的workerId不应该分配: %d", released)
+	}
+	// 节点退出后分配记录仍然保留
+	kvs, _, err := etcd.ListWithContext(context.Background(), "assign/history/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var recorded bool
+	for _, kv := range kvs {
+		var assignment model.Assignment
+		if err = json.Unmarshal(kv.Value, &assignment); err == nil && assignment.WorkerId == released &&
+			assignment.AssignTime.After(start) && kv.Lease == 0 {
+			recorded = true
+		}
+	}
+	if !recorded {
+		t.Fatalf("应该保留workerId %d 的分配记录", released)
+	}
+	if _, err = etcd.GetWithContext(context.Background(), expired); !basic.IsNotFound(err) {
+		t.Fatalf("过期的分配记录应该被删除: %v", err)
+	}
+
+	// leader退出后由其他节点接替分配，leader释放的workerId同样可能进入冷却期
+	_ = first.Close()
+	time.Sleep(config.WorkerCooldown + time.Second)
+	fourth, err := New(etcdConfig, config)
+	if err != nil {
+		t.Fatal("leader退出后应该可以分配", err)
+	}
+	defer fourth.Close()
+	if fourth.Info().WorkerId != 9 {
+		t.Fatalf("应该分配释放的保留workerId: %d", fourth.Info().WorkerId)
+	}
+}
+
+// 模拟两个节点没有在worker/下注册，直接使用了相同的dataCenterId和workerId
+func TestDetector_Conflict(t *testing.T) {
+	etcd, err := basic.NewEtcd(testEtcdConfig(t))
+	if err != nil {
+		t.Fatal("连接etcd失败", err)
+	}
+	defer etcd.Close()
+
+	twepoch, _ := time.Parse(time.RFC3339, basic.DefaultTwepoch)
+	workers := make([]*model.IdWorker, 2)
+	for i := range workers {
+		if workers[i], err = model.NewIdWorker(31, 31, twepoch); err != nil {
+			t.Fatal("创建IdWorker失败", err)
+		}
+	}
+
+	first, err := model.StartDetector(etcd, workers[0], time.Second)
+	if err != nil {
+		t.Fatal("启动检测失败", err)
+	}
+	defer first.Close()
+	if _, err = workers[0].NextId(); err != nil {
+		t.Fatal("没有冲突时应该可以发号", err)
+	}
+
+	// 后启动的节点读取到已有节点，立即停止发号
+	second, err := model.StartDetector(etcd, workers[1], time.Second)
+	if err != nil {
+		t.Fatal("启动检测失败", err)
+	}
+	defer second.Close()
+	if _, err = workers[1].NextId(); err == nil {
+		t.Fatal("检测到冲突后应该停止发号")
+	}
+	// 先启动的节点通过watch发现冲突
+	for deadline := time.Now().Add(3 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if _, err = workers[0].NextId(); err != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("先启动的节点没有检测到冲突")
+		}
+	}
+}
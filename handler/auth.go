@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"github.com/LazzyQ/msnowflake/basic"
+	microErrors "github.com/micro/go-micro/v2/errors"
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/micro/go-micro/v2/server"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"strings"
+)
+
+const (
+	AuthMetadataKey = "Authorization"
+	bearerPrefix    = "Bearer "
+)
+
+var (
+	auth *authenticator
+)
+
+type identityKey struct{}
+
+type credential struct {
+	identity string
+	token    []byte
+}
+
+type authenticator struct {
+	credentials []credential
+	allow       map[string]bool // 为空时不限制
+}
+
+// 配置了token或允许列表时开启认证
+func InitAuth(config basic.AuthConfig) error {
+	if len(config.Tokens) == 0 && len(config.Allow) == 0 {
+		auth = nil
+		return nil
+	}
+	a := &authenticator{allow: make(map[string]bool, len(config.Allow))}
+	for i, s := range config.Tokens {
+		parts := strings.SplitN(s, "=", 2)
+		if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+			// 格式不正确时parts[0]可能就是token，只记录是第几项配置
+			zap.S().Errorw("token配置格式不正确，应该为identity=token", "index", i)
+			return errors.New("token配置格式不正确")
+		}
+		a.credentials = append(a.credentials, credential{identity: parts[0], token: []byte(parts[1])})
+	}
+	for _, identity := range config.Allow {
+		a.allow[identity] = true
+	}
+	auth = a
+	zap.S().Infow("开启认证", "token数", len(a.credentials), "允许的调用方", config.Allow)
+	return nil
+}
+
+// 认证调用方身份，token优先，没有token时使用双向TLS中客户端证书的CN
+func AuthWrapper(fn server.HandlerFunc) server.HandlerFunc {
+	return func(ctx context.Context, req server.Request, rsp interface{}) error {
+		if auth == nil {
+			return fn(ctx, req, rsp)
+		}
+		identity, err := auth.authenticate(ctx)
+		if err != nil {
+			zap.S().Warnw("认证失败", "requestId", requestIdOf(ctx), "method", req.Endpoint(), "err", err)
+			return microErrors.Unauthorized(req.Service(), err.Error())
+		}
+		if len(auth.allow) > 0 && !auth.allow[identity] {
+			zap.S().Warnw("调用方不在允许列表中", "requestId", requestIdOf(ctx), "identity", identity, "method", req.Endpoint())
+			return microErrors.Forbidden(req.Service(), fmt.Sprintf("调用方%s没有访问权限", identity))
+		}
+		return fn(context.WithValue(ctx, identityKey{}, identity), req, rsp)
+	}
+}
+
+func (a *authenticator) authenticate(ctx context.Context) (string, error) {
+	if value, ok := metadata.Get(ctx, AuthMetadataKey); ok && len(value) > 0 {
+		if !strings.HasPrefix(value, bearerPrefix) {
+			return "", errors.New("token格式不正确")
+		}
+		token := []byte(strings.TrimPrefix(value, bearerPrefix))
+		for _, c := range a.credentials {
+			if subtle.ConstantTimeCompare(c.token, token) == 1 {
+				return c.identity, nil
+			}
+		}
+		return "", errors.New("token不正确")
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.VerifiedChains) > 0 {
+			if cn := info.State.VerifiedChains[0][0].Subject.CommonName; len(cn) > 0 {
+				return cn, nil
+			}
+		}
+	}
+	return "", errors.New("缺少token或客户端证书")
+}
+
+// 认证通过的调用方身份
+func identityOf(ctx context.Context) (string, bool) {
+	identity, ok := ctx.Value(identityKey{}).(string)
+	return identity, ok
+}
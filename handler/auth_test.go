@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/LazzyQ/msnowflake/basic"
+	msnowflake "github.com/LazzyQ/msnowflake/proto"
+	"github.com/micro/go-micro/v2/codec"
+	microErrors "github.com/micro/go-micro/v2/errors"
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/micro/go-micro/v2/server"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"testing"
+)
+
+type testRequest struct {
+	endpoint string
+	body     interface{}
+}
+
+func (r *testRequest) Service() string           { return "msnowflake" }
+func (r *testRequest) Method() string            { return r.endpoint }
+func (r *testRequest) Endpoint() string          { return r.endpoint }
+func (r *testRequest) ContentType() string       { return "application/grpc+proto" }
+func (r *testRequest) Header() map[string]string { return nil }
+func (r *testRequest) Body() interface{}         { return r.body }
+func (r *testRequest) Read() ([]byte, error)     { return nil, nil }
+func (r *testRequest) Codec() codec.Reader       { return nil }
+func (r *testRequest) Stream() bool              { return false }
+
+// 返回认证后的调用方和AuthWrapper返回的错误，fn返回handler的错误
+func authCall(ctx context.Context, body interface{}) (string, error) {
+	var caller string
+	fn := AuthWrapper(func(ctx context.Context, req server.Request, rsp interface{}) error {
+		caller = requestCaller(ctx, req)
+		return nil
+	})
+	err := fn(ctx, &testRequest{endpoint: "MSnowflake.NextId", body: body}, &msnowflake.IdResponse{})
+	return caller, err
+}
+
+func TestInitAuth(t *testing.T) {
+	defer func() { auth = nil }()
+	if err := InitAuth(basic.AuthConfig{Tokens: []string{"secret-token"}}); err == nil {
+		t.Fatal("缺少identity时应该返回错误")
+	}
+	if err := InitAuth(basic.AuthConfig{}); err != nil || auth != nil {
+		t.Fatal("没有配置时不应该开启认证", err)
+	}
+}
+
+func TestAuthWrapper_Token(t *testing.T) {
+	if err := InitAuth(basic.AuthConfig{Tokens: []string{"job=secret"}}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { auth = nil }()
+
+	ctx := metadata.NewContext(context.Background(), metadata.Metadata{AuthMetadataKey: "Bearer secret"})
+	// 认证的身份优先于请求中声明的调用方
+	if caller, err := authCall(ctx, &msnowflake.IdRequest{Caller: "other"}); err != nil || caller != "job" {
+		t.Fatal("token正确时应该认证为job", caller, err)
+	}
+
+	for _, value := range []string{"Bearer wrong", "secret", ""} {
+		ctx := metadata.NewContext(context.Background(), metadata.Metadata{AuthMetadataKey: value})
+		if _, err := authCall(ctx, &msnowflake.IdRequest{}); err == nil || microErrors.Parse(err.Error()).Code != 401 {
+			t.Errorf("%q 应该认证失败, err:%v", value, err)
+		}
+	}
+}
+
+func TestAuthWrapper_ClientCert(t *testing.T) {
+	if err := InitAuth(basic.AuthConfig{Allow: []string{"job"}}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { auth = nil }()
+
+	peerOf := func(cn string) context.Context {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: cn}}
+		return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{
+			State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}},
+		}})
+	}
+	if caller, err := authCall(peerOf("job"), &msnowflake.IdRequest{}); err != nil || caller != "job" {
+		t.Fatal("应该使用客户端证书的CN作为身份", caller, err)
+	}
+	if _, err := authCall(peerOf("other"), &msnowflake.IdRequest{}); err == nil || microErrors.Parse(err.Error()).Code != 403 {
+		t.Fatal("不在允许列表中的调用方应该被拒绝", err)
+	}
+}
+
+func TestCallerOf(t *testing.T) {
+	ctx := metadata.NewContext(context.Background(), metadata.Metadata{CallerMetadataKey: "from-metadata"})
+	if caller := callerOf(ctx, &msnowflake.IdRequest{Caller: "from-request"}); caller != "from-request" {
+		t.Error("应该优先使用请求中的caller", caller)
+	}
+	if caller := callerOf(ctx, &msnowflake.IdRequest{}); caller != "from-metadata" {
+		t.Error("请求中没有caller时应该使用metadata", caller)
+	}
+	if caller := callerOf(context.Background(), &msnowflake.IdRequest{}); caller != anonymousCaller {
+		t.Error("没有调用方标识时应该为anonymous", caller)
+	}
+	ctx = context.WithValue(ctx, identityKey{}, "job")
+	if caller := callerOf(ctx, &msnowflake.IdRequest{Caller: "from-request"}); caller != "job" {
+		t.Error("应该优先使用认证的身份", caller)
+	}
+}
@@ -4,20 +4,34 @@ import (
 	"context"
 	"github.com/LazzyQ/msnowflake/model"
 	msnowflake "github.com/LazzyQ/msnowflake/proto"
+	microErrors "github.com/micro/go-micro/v2/errors"
+	"time"
+)
+
+const (
+	ServiceName = "go.micro.srv.snowflake"
+	// 调用方可以在metadata中指定请求id，写入审计日志并在响应中返回，未指定时由服务端生成
+	RequestIdMetadataKey = "Msnowflake-Request-Id"
 )
 
 var (
 	idWorder *model.IdWorker
+	limiter  *model.Limiter
 )
 
 type MSnowflake struct {
 }
 
 func (m MSnowflake) NextId(ctx context.Context, req *msnowflake.IdRequest, res *msnowflake.IdResponse) error {
-	id, err := idWorder.NextId()
+	id, err := idWorder.NextIdWithContext(requestContext(ctx, req))
 	if err != nil {
 		return err
 	}
+	if req.Encoding != msnowflake.Encoding_NONE {
+		if res.IdStr, err = model.Encode(id, model.Encoding(req.Encoding)); err != nil {
+			return invalidArgument(err)
+		}
+	}
 	res.Code = 0
 	res.Message = "success"
 	res.Id = id
@@ -25,17 +39,181 @@ func (m MSnowflake) NextId(ctx context.Context, req *msnowflake.IdRequest, res *
 }
 
 func (m MSnowflake) NextIds(ctx context.Context, req *msnowflake.IdRequest, res *msnowflake.IdResponse) error {
-	ids, err := idWorder.NextIds(req.Num)
+	if req.Num > model.MaxNextIdsNum {
+		return microErrors.BadRequest(ServiceName, "NextIds数量参数不对: %d", req.Num)
+	}
+	ids, err := idWorder.NextIdsWithContext(requestContext(ctx, req), req.Num)
 	if err != nil {
 		return err
 	}
+	if req.Encoding != msnowflake.Encoding_NONE {
+		if res.IdsStr, err = encodeIds(ids, req.Encoding); err != nil {
+			return invalidArgument(err)
+		}
+	}
 	res.Code = 0
 	res.Message = "success"
 	res.Ids = ids
 	return nil
 }
 
+func (m MSnowflake) Encode(ctx context.Context, req *msnowflake.EncodeRequest, res *msnowflake.EncodeResponse) (err error) {
+	if res.IdsStr, err = encodeIds(req.Ids, req.Encoding); err != nil {
+		return invalidArgument(err)
+	}
+	res.Code = 0
+	res.Message = "success"
+	return nil
+}
+
+func (m MSnowflake) Decode(ctx context.Context, req *msnowflake.DecodeRequest, res *msnowflake.DecodeResponse) error {
+	ids := make([]int64, len(req.IdsStr))
+	for i, s := range req.IdsStr {
+		id, err := model.Decode(s, model.Encoding(req.Encoding))
+		if err != nil {
+			return invalidArgument(err)
+		}
+		ids[i] = id
+	}
+	res.Code = 0
+	res.Message = "success"
+	res.Ids = ids
+	return nil
+}
+
+// 还原混淆后的id，仅供内部工具使用
+func (m MSnowflake) Reveal(ctx context.Context, req *msnowflake.RevealRequest, res *msnowflake.RevealResponse) error {
+	ids := make([]int64, len(req.Ids))
+	for i, v := range req.Ids {
+		id, err := idWorder.Reveal(v)
+		if err != nil {
+			return invalidArgument(err)
+		}
+		ids[i] = id
+	}
+	res.Code = 0
+	res.Message = "success"
+	res.Ids = ids
+	return nil
+}
+
+// num为0时返回1个
+func (m MSnowflake) NextUlids(ctx context.Context, req *msnowflake.IdRequest, res *msnowflake.BytesIdResponse) error {
+	ulids, err := idWorder.NextUlidsWithContext(requestContext(ctx, req), batchNum(req.Num))
+	if err != nil {
+		return err
+	}
+	res.Code = 0
+	res.Message = "success"
+	for i := range ulids {
+		res.Ids = append(res.Ids, ulids[i][:])
+		res.IdsStr = append(res.IdsStr, ulids[i].String())
+	}
+	return nil
+}
+
+// num为0时返回1个
+func (m MSnowflake) NextUuids(ctx context.Context, req *msnowflake.IdRequest, res *msnowflake.BytesIdResponse) error {
+	uuids, err := idWorder.NextUuidsWithContext(requestContext(ctx, req), batchNum(req.Num))
+	if err != nil {
+		return err
+	}
+	res.Code = 0
+	res.Message = "success"
+	for i := range uuids {
+		res.Ids = append(res.Ids, uuids[i][:])
+		res.IdsStr = append(res.IdsStr, uuids[i].String())
+	}
+	return nil
+}
+
+func (m MSnowflake) Info(ctx context.Context, req *msnowflake.InfoRequest, res *msnowflake.InfoResponse) error {
+	info := idWorder.Info()
+	res.Code = 0
+	res.Message = "success"
+	res.Layout = info.Layout.String()
+	res.TimeUnitMs = int64(info.Layout.TimeUnit / time.Millisecond)
+	res.TimestampBits = uint32(info.Layout.TimestampBits)
+	res.DatacenterBits = uint32(info.Layout.DataCenterBits)
+	res.WorkerBits = uint32(info.Layout.WorkerBits)
+	res.SequenceBits = uint32(info.Layout.SequenceBits)
+	res.DatacenterId = info.DataCenterId
+	res.WorkerId = info.WorkerId
+	res.Twepoch = unixMillis(info.Twepoch)
+	res.ExhaustTime = unixMillis(info.ExhaustTime)
+	res.ServeUntil = unixMillis(info.ServeUntil)
+	res.Obfuscated = info.Obfuscated
+	return nil
+}
+
+func (m MSnowflake) Range(ctx context.Context, req *msnowflake.RangeRequest, res *msnowflake.RangeResponse) (err error) {
+	dataCenterId, workerId := int64(model.AnyId), int64(model.AnyId)
+	if req.HasDatacenterId {
+		dataCenterId = req.DatacenterId
+	}
+	if req.HasWorkerId {
+		workerId = req.WorkerId
+	}
+	min, max, err := idWorder.IdRange(fromUnixMillis(req.StartTime), fromUnixMillis(req.EndTime), dataCenterId, workerId)
+	if err != nil {
+		return invalidArgument(err)
+	}
+	if req.Encoding != msnowflake.Encoding_NONE {
+		if res.MinIdStr, err = model.Encode(min, model.Encoding(req.Encoding)); err != nil {
+			return invalidArgument(err)
+		}
+		if res.MaxIdStr, err = model.Encode(max, model.Encoding(req.Encoding)); err != nil {
+			return invalidArgument(err)
+		}
+	}
+	res.Code = 0
+	res.Message = "success"
+	res.MinId = min
+	res.MaxId = max
+	return nil
+}
+
 func Init() (err error) {
-	idWorder, err = model.GetIdWorker()
+	if idWorder, err = model.GetIdWorker(); err != nil {
+		return err
+	}
+	limiter, err = model.GetLimiter()
 	return err
 }
+
+// 把调用方和请求id放到ctx中，发号时写入审计日志
+func requestContext(ctx context.Context, req *msnowflake.IdRequest) context.Context {
+	return model.NewRequestContext(ctx, model.RequestInfo{Caller: callerOf(ctx, req), RequestId: requestIdOf(ctx)})
+}
+
+func encodeIds(ids []int64, encoding msnowflake.Encoding) ([]string, error) {
+	strs := make([]string, len(ids))
+	for i, id := range ids {
+		s, err := model.Encode(id, model.Encoding(encoding))
+		if err != nil {
+			return nil, err
+		}
+		strs[i] = s
+	}
+	return strs, nil
+}
+
+// 请求参数不正确的错误原样返回给调用方，其他错误由RecoveryWrapper替换为内部错误
+func invalidArgument(err error) error {
+	return microErrors.BadRequest(ServiceName, err.Error())
+}
+
+func batchNum(num uint32) uint32 {
+	if num == 0 {
+		return 1
+	}
+	return num
+}
+
+func unixMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func fromUnixMillis(ms int64) time.Time {
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
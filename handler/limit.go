@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"context"
+	"github.com/LazzyQ/msnowflake/model"
+	msnowflake "github.com/LazzyQ/msnowflake/proto"
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/micro/go-micro/v2/server"
+	"go.uber.org/zap"
+)
+
+const (
+	CodeRateLimited   int32 = 429 // 请求过于频繁
+	CodeQuotaExceeded int32 = 430 // 当日配额已用完
+
+	// IdRequest没有指定caller时从metadata中获取调用方标识
+	CallerMetadataKey = "Msnowflake-Caller"
+	anonymousCaller   = "anonymous"
+)
+
+// 按调用方限流的wrapper，只作用于获取id的请求，被拒绝时返回code不为0的响应
+func LimitWrapper(fn server.HandlerFunc) server.HandlerFunc {
+	return func(ctx context.Context, req server.Request, rsp interface{}) error {
+		idReq, ok := req.Body().(*msnowflake.IdRequest)
+		if !ok || limiter == nil {
+			return fn(ctx, req, rsp)
+		}
+
+		caller := callerOf(ctx, idReq)
+		n := requestNum(req)
+		err := limiter.Allow(caller, n)
+		if err == nil {
+			if err = fn(ctx, req, rsp); err != nil {
+				// 获取失败的id不计入统计和配额
+				limiter.Refund(caller, n)
+			}
+			return err
+		}
+
+		code := CodeRateLimited
+		if err == model.ErrQuotaExceeded {
+			code = CodeQuotaExceeded
+		}
+		zap.S().Debugw("请求被限流", "caller", caller, "method", req.Endpoint(), "num", n, "err", err)
+		switch res := rsp.(type) {
+		case *msnowflake.IdResponse:
+			res.Code, res.Message = code, err.Error()
+		case *msnowflake.BytesIdResponse:
+			res.Code, res.Message = code, err.Error()
+		default:
+			return err
+		}
+		return nil
+	}
+}
+
+// 调用方标识，开启认证时使用认证的身份，否则优先使用IdRequest中的caller。
+// 没有开启认证时caller和metadata都由客户端指定，可以冒充单独配置了限流的调用方
+func callerOf(ctx context.Context, req *msnowflake.IdRequest) string {
+	if identity, ok := identityOf(ctx); ok {
+		return identity
+	}
+	if len(req.Caller) > 0 {
+		return req.Caller
+	}
+	if caller, ok := metadata.Get(ctx, CallerMetadataKey); ok && len(caller) > 0 {
+		return caller
+	}
+	return anonymousCaller
+}
+
+func (m MSnowflake) Stats(ctx context.Context, req *msnowflake.StatsRequest, res *msnowflake.StatsResponse) error {
+	res.Code = 0
+	res.Message = "success"
+	for _, s := range limiter.Stats() {
+		res.Callers = append(res.Callers, &msnowflake.CallerStats{
+			Caller:        s.Caller,
+			Rate:          s.Limit.Rate,
+			Burst:         int64(s.Limit.Burst),
+			DailyQuota:    s.Limit.DailyQuota,
+			Issued:        s.Issued,
+			RateLimited:   s.RateLimited,
+			QuotaExceeded: s.QuotaExceeded,
+			QuotaUsed:     s.QuotaUsed,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"github.com/LazzyQ/msnowflake/basic"
+	"github.com/LazzyQ/msnowflake/model"
+	msnowflake "github.com/LazzyQ/msnowflake/proto"
+	microErrors "github.com/micro/go-micro/v2/errors"
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/micro/go-micro/v2/server"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	grpcMetadata "google.golang.org/grpc/metadata"
+	"math"
+	"runtime/debug"
+	"time"
+)
+
+// 调用方指定的请求id超过该长度时由服务端重新生成
+const maxRequestIdLength = 128
+
+var (
+	// 没有返回错误的请求的日志，按配置采样
+	requestLogger *zap.SugaredLogger
+)
+
+type requestIdKey struct{}
+
+func InitRequestLog(config basic.RequestLogConfig) {
+	if config.First <= 0 {
+		requestLogger = nil
+		return
+	}
+	thereafter := config.Thereafter
+	if thereafter <= 0 {
+		thereafter = math.MaxInt32
+	}
+	requestLogger = zap.New(zapcore.NewSampler(zap.L().Core(), time.Second, config.First, thereafter)).Sugar()
+}
+
+// 为请求分配请求id，调用方可以在metadata中指定。所有响应(包括返回错误的响应)的header中都带上请求id，
+// 获取id的响应体中同样带上
+func RequestIdWrapper(fn server.HandlerFunc) server.HandlerFunc {
+	return func(ctx context.Context, req server.Request, rsp interface{}) error {
+		requestId, _ := metadata.Get(ctx, RequestIdMetadataKey)
+		if len(requestId) == 0 || len(requestId) > maxRequestIdLength {
+			var err error
+			if requestId, err = model.NewRequestId(); err != nil {
+				return err
+			}
+		}
+		ctx = context.WithValue(ctx, requestIdKey{}, requestId)
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("msnowflake.request_id", requestId))
+		// 不是grpc的请求时ctx中没有stream，无法设置header
+		_ = grpc.SetHeader(ctx, grpcMetadata.Pairs(RequestIdMetadataKey, requestId))
+
+		err := fn(ctx, req, rsp)
+		switch res := rsp.(type) {
+		case *msnowflake.IdResponse:
+			res.RequestId = requestId
+		case *msnowflake.BytesIdResponse:
+			res.RequestId = requestId
+		}
+		return err
+	}
+}
+
+// 每个请求记录一条日志，返回错误的请求都记录，其他请求按配置采样
+func LogWrapper(fn server.HandlerFunc) server.HandlerFunc {
+	return func(ctx context.Context, req server.Request, rsp interface{}) error {
+		start := time.Now()
+		err := fn(ctx, req, rsp)
+
+		code, message := responseCode(rsp)
+		if err != nil {
+			// 不是go-micro的错误时按500返回给调用方
+			if code = microErrors.Parse(err.Error()).Code; code == 0 {
+				code = 500
+			}
+			message = err.Error()
+		} else if requestLogger == nil {
+			return nil
+		}
+		fields := []interface{}{
+			"requestId", requestIdOf(ctx),
+			"caller", requestCaller(ctx, req),
+			"method", req.Endpoint(),
+			"num", requestNum(req),
+			"latency", time.Since(start),
+			"code", code,
+		}
+		switch {
+		case err != nil:
+			zap.S().Warnw("请求失败", append(fields, "err", message)...)
+		case code != 0:
+			// 被限流的请求可能很多，同样采样
+			requestLogger.Infow("请求被拒绝", append(fields, "message", message)...)
+		default:
+			requestLogger.Infow("请求完成", fields...)
+		}
+		return err
+	}
+}
+
+// 把panic转换为错误返回，避免进程退出。
+// handler方法中的panic由go-micro恢复并返回带panic内容的500错误，500错误和不是go-micro的错误(如时钟回拨、etcd错误)
+// 都可能包含内部信息，记录日志后替换为不含内部信息的错误
+func RecoveryWrapper(fn server.HandlerFunc) server.HandlerFunc {
+	return func(ctx context.Context, req server.Request, rsp interface{}) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				zap.S().Errorw("处理请求时发生panic",
+					"requestId", requestIdOf(ctx),
+					"method", req.Endpoint(),
+					"panic", fmt.Sprint(r),
+					"stack", string(debug.Stack()))
+				err = internalError(ctx, req)
+			}
+		}()
+		if err = fn(ctx, req, rsp); err != nil && isInternal(err) {
+			zap.S().Errorw("处理请求时发生内部错误", "requestId", requestIdOf(ctx), "method", req.Endpoint(), "err", err)
+			err = internalError(ctx, req)
+		}
+		return err
+	}
+}
+
+func isInternal(err error) bool {
+	e, ok := err.(*microErrors.Error)
+	return !ok || e.Code == 500
+}
+
+func internalError(ctx context.Context, req server.Request) error {
+	return microErrors.InternalServerError(req.Service(), "服务内部错误, requestId:%s", requestIdOf(ctx))
+}
+
+func requestIdOf(ctx context.Context) string {
+	requestId, _ := ctx.Value(requestIdKey{}).(string)
+	return requestId
+}
+
+// 获取id的请求返回调用方标识，其他请求只使用认证身份或metadata
+func requestCaller(ctx context.Context, req server.Request) string {
+	if idReq, ok := req.Body().(*msnowflake.IdRequest); ok {
+		return callerOf(ctx, idReq)
+	}
+	return callerOf(ctx, &msnowflake.IdRequest{})
+}
+
+// 获取id的请求的数量，NextId为1，其他请求num为0时按1个计算，不是获取id的请求返回0
+func requestNum(req server.Request) int {
+	idReq, ok := req.Body().(*msnowflake.IdRequest)
+	if !ok {
+		return 0
+	}
+	if req.Endpoint() == "MSnowflake.NextId" {
+		return 1
+	}
+	return int(batchNum(idReq.Num))
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/LazzyQ/msnowflake/basic"
+	msnowflake "github.com/LazzyQ/msnowflake/proto"
+	microErrors "github.com/micro/go-micro/v2/errors"
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/micro/go-micro/v2/server"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	grpcMetadata "google.golang.org/grpc/metadata"
+	"strings"
+	"testing"
+)
+
+// 记录handler设置的grpc header
+type testStream struct {
+	header grpcMetadata.MD
+}
+
+func (s *testStream) Method() string { return "" }
+
+func (s *testStream) SetHeader(md grpcMetadata.MD) error {
+	s.header = grpcMetadata.Join(s.header, md)
+	return nil
+}
+
+func (s *testStream) SendHeader(md grpcMetadata.MD) error { return s.SetHeader(md) }
+
+func (s *testStream) SetTrailer(md grpcMetadata.MD) error { return nil }
+
+func TestRequestIdWrapper(t *testing.T) {
+	fn := RequestIdWrapper(func(ctx context.Context, req server.Request, rsp interface{}) error {
+		if requestIdOf(ctx) == "" {
+			t.Error("ctx中应该有请求id")
+		}
+		if _, ok := rsp.(*msnowflake.InfoResponse); ok {
+			return errors.New("failed")
+		}
+		return nil
+	})
+
+	stream := &testStream{}
+	ctx := metadata.NewContext(context.Background(), metadata.Metadata{RequestIdMetadataKey: "req-1"})
+	ctx = grpc.NewContextWithServerTransportStream(ctx, stream)
+	res := &msnowflake.IdResponse{}
+	if err := fn(ctx, &testRequest{endpoint: "MSnowflake.NextId", body: &msnowflake.IdRequest{}}, res); err != nil {
+		t.Fatal(err)
+	}
+	if res.RequestId != "req-1" || stream.header.Get(RequestIdMetadataKey)[0] != "req-1" {
+		t.Fatalf("应该使用调用方指定的请求id, requestId:%s header:%v", res.RequestId, stream.header)
+	}
+
+	// 其他请求和返回错误的请求同样在header中带上请求id，调用方没有指定或太长时重新生成
+	for _, requestId := range []string{"", strings.Repeat("a", maxRequestIdLength+1)} {
+		stream := &testStream{}
+		ctx := metadata.NewContext(context.Background(), metadata.Metadata{RequestIdMetadataKey: requestId})
+		ctx = grpc.NewContextWithServerTransportStream(ctx, stream)
+		if err := fn(ctx, &testRequest{endpoint: "MSnowflake.Info", body: &msnowflake.InfoRequest{}}, &msnowflake.InfoResponse{}); err == nil {
+			t.Fatal("应该返回handler的错误")
+		}
+		if values := stream.header.Get(RequestIdMetadataKey); len(values) != 1 || len(values[0]) == 0 || values[0] == requestId {
+			t.Fatalf("应该重新生成请求id, header:%v", stream.header)
+		}
+	}
+}
+
+func TestRecoveryWrapper(t *testing.T) {
+	ctx := context.WithValue(context.Background(), requestIdKey{}, "req-1")
+	req := &testRequest{endpoint: "MSnowflake.Info", body: &msnowflake.InfoRequest{}}
+	call := func(handlerErr error) error {
+		return RecoveryWrapper(func(ctx context.Context, req server.Request, rsp interface{}) error {
+			if handlerErr == nil {
+				panic("secret")
+			}
+			return handlerErr
+		})(ctx, req, &msnowflake.InfoResponse{})
+	}
+
+	// wrapper中的panic、go-micro恢复handler的panic后返回的错误和不是go-micro的错误都不包含内部信息
+	for _, handlerErr := range []error{nil, microErrors.InternalServerError("go.micro.server", "panic recovered: secret"), errors.New("etcd: secret")} {
+		err := call(handlerErr)
+		if err == nil {
+			t.Fatal("应该返回错误")
+		}
+		if e := microErrors.Parse(err.Error()); e.Code != 500 || e.Detail != "服务内部错误, requestId:req-1" {
+			t.Fatalf("错误不正确: %v", err)
+		}
+	}
+	// 其他错误原样返回
+	if err := call(microErrors.BadRequest("msnowflake", "参数不正确")); microErrors.Parse(err.Error()).Detail != "参数不正确" {
+		t.Fatalf("错误不正确: %v", err)
+	}
+}
+
+func TestLogWrapper(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	defer zap.ReplaceGlobals(zap.New(core))()
+	InitRequestLog(basic.RequestLogConfig{First: 1})
+	defer func() { requestLogger = nil }()
+
+	ctx := context.WithValue(context.Background(), requestIdKey{}, "req-1")
+	req := &testRequest{endpoint: "MSnowflake.NextIds", body: &msnowflake.IdRequest{Caller: "job", Num: 10}}
+	ok := LogWrapper(func(ctx context.Context, req server.Request, rsp interface{}) error { return nil })
+	failed := LogWrapper(func(ctx context.Context, req server.Request, rsp interface{}) error {
+		return microErrors.BadRequest("msnowflake", "参数不正确")
+	})
+
+	// 成功的请求按配置采样，每秒只记录第一条
+	for i := 0; i < 3; i++ {
+		_ = ok(ctx, req, &msnowflake.IdResponse{})
+	}
+	_ = failed(ctx, req, &msnowflake.IdResponse{})
+	_ = failed(ctx, req, &msnowflake.IdResponse{})
+
+	entries := logs.AllUntimed()
+	if len(entries) != 3 || entries[0].Message != "请求完成" || entries[1].Message != "请求失败" {
+		t.Fatalf("日志不正确: %+v", entries)
+	}
+	fields := entries[1].ContextMap()
+	if fields["requestId"] != "req-1" || fields["caller"] != "job" || fmt.Sprint(fields["num"]) != "10" || fmt.Sprint(fields["code"]) != "400" {
+		t.Fatalf("日志字段不正确: %v", fields)
+	}
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"context"
+	"github.com/LazzyQ/msnowflake/basic"
+	msnowflake "github.com/LazzyQ/msnowflake/proto"
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/micro/go-micro/v2/server"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+	"strings"
+)
+
+var tracer = otel.Tracer("github.com/LazzyQ/msnowflake/handler")
+
+// 从go-micro的metadata中读取上游的trace context，metadata的key会被转换为首字母大写
+type metadataCarrier metadata.Metadata
+
+func (c metadataCarrier) Get(key string) string {
+	if v, ok := c[key]; ok {
+		return v
+	}
+	return c[strings.Title(key)]
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// 为每个请求创建server span，需要放在最外层，认证和限流的结果也会记录在span中
+func TraceWrapper(fn server.HandlerFunc) server.HandlerFunc {
+	return func(ctx context.Context, req server.Request, rsp interface{}) (err error) {
+		if md, ok := metadata.FromContext(ctx); ok {
+			ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+		}
+		service, method := req.Endpoint(), req.Endpoint()
+		if i := strings.Index(method, "."); i >= 0 {
+			service, method = method[:i], method[i+1:]
+		}
+		ctx, span := tracer.Start(ctx, req.Endpoint(),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.RPCSystemKey.String("grpc"),
+				semconv.RPCServiceKey.String(service),
+				semconv.RPCMethodKey.String(method),
+			))
+		defer func() { basic.EndSpan(span, err) }()
+		if idReq, ok := req.Body().(*msnowflake.IdRequest); ok {
+			span.SetAttributes(attribute.Int64("msnowflake.num", int64(idReq.Num)))
+		}
+
+		if err = fn(ctx, req, rsp); err != nil {
+			return err
+		}
+		// 业务错误通过响应中的code返回
+		if code, message := responseCode(rsp); code != 0 {
+			span.SetAttributes(attribute.Int64("msnowflake.code", int64(code)))
+			span.SetStatus(codes.Error, message)
+		}
+		return nil
+	}
+}
+
+// 各响应都带有code和message
+type codeResponse interface {
+	GetCode() int32
+	GetMessage() string
+}
+
+func responseCode(rsp interface{}) (int32, string) {
+	if res, ok := rsp.(codeResponse); ok {
+		return res.GetCode(), res.GetMessage()
+	}
+	return 0, ""
+}
@@ -2,24 +2,33 @@ package main
 
 import (
 	"github.com/LazzyQ/msnowflake/basic"
+	"github.com/LazzyQ/msnowflake/command"
 	"github.com/LazzyQ/msnowflake/handler"
 	"github.com/LazzyQ/msnowflake/model"
 	"github.com/LazzyQ/msnowflake/proto"
 	"github.com/micro/cli/v2"
 	"github.com/micro/go-micro/v2"
-	"github.com/micro/go-micro/v2/registry"
+	grpcServer "github.com/micro/go-micro/v2/server/grpc"
 	"go.uber.org/zap"
 	"strings"
 	"time"
 )
 
+const serviceName = handler.ServiceName
+
 func main() {
 	logConfig := basic.LogConfig{}
 	etcdConfig := basic.EtcdConfig{}
 	snowflakeConfig := basic.SnowflakeConfig{}
+	limitConfig := basic.LimitConfig{}
+	tlsConfig := basic.TLSConfig{}
+	authConfig := basic.AuthConfig{}
+	journalConfig := basic.JournalConfig{}
+	traceConfig := basic.TraceConfig{ServiceName: serviceName}
+	requestLogConfig := basic.RequestLogConfig{}
 
 	srv := micro.NewService(
-		micro.Name("go.micro.srv.snowflake"),
+		micro.Name(serviceName),
 		micro.Flags(
 			&cli.StringFlag{
 				Name:        "log_filename",
@@ -45,6 +54,18 @@ func main() {
 				Value:       30,
 				Destination: &logConfig.MaxAge,
 			},
+			&cli.IntFlag{
+				Name:        "log_request_first",
+				Usage:       "请求日志采样: 每秒内前N个请求都记录，为0时只记录返回错误的请求，返回错误的请求不采样",
+				Value:       100,
+				Destination: &requestLogConfig.First,
+			},
+			&cli.IntFlag{
+				Name:        "log_request_thereafter",
+				Usage:       "请求日志采样: 超过log_request_first后每N个请求记录一条",
+				Value:       100,
+				Destination: &requestLogConfig.Thereafter,
+			},
 			&cli.StringFlag{
 				Name:  "etcd_address",
 				Usage: "etcd集群地址",
@@ -60,12 +81,85 @@ func main() {
 				Usage: "etcd集群超时时间(s)",
 				Value: 2,
 			},
+			&cli.StringFlag{
+				Name:        "etcd_namespace",
+				Usage:       "所有key的前缀，共用一个etcd的多套部署(如测试和生产)使用不同的前缀，workerId只在同一个前缀下互不重复",
+				Value:       basic.DefaultEtcdNamespace,
+				EnvVars:     []string{"MSNOWFLAKE_ETCD_NAMESPACE"},
+				Destination: &etcdConfig.Namespace,
+			},
+			&cli.IntFlag{
+				Name:        "etcd_max_retries",
+				Usage:       "etcd暂时不可用或请求超时时的最大重试次数，为0时不重试",
+				Value:       3,
+				Destination: &etcdConfig.MaxRetries,
+			},
+			&cli.DurationFlag{
+				Name:        "etcd_retry_backoff",
+				Usage:       "etcd第一次重试前等待的时间，之后每次翻倍",
+				Value:       100 * time.Millisecond,
+				Destination: &etcdConfig.RetryBackoff,
+			},
+			&cli.DurationFlag{
+				Name:        "etcd_max_retry_backoff",
+				Usage:       "etcd重试前最多等待的时间",
+				Value:       2 * time.Second,
+				Destination: &etcdConfig.MaxRetryBackoff,
+			},
+			&cli.StringFlag{
+				Name:        "etcd_tls_cert",
+				Usage:       "连接etcd的客户端证书",
+				Destination: &etcdConfig.TLS.CertFile,
+			},
+			&cli.StringFlag{
+				Name:        "etcd_tls_key",
+				Usage:       "连接etcd的客户端私钥",
+				Destination: &etcdConfig.TLS.KeyFile,
+			},
+			&cli.StringFlag{
+				Name:        "etcd_tls_ca",
+				Usage:       "校验etcd服务端证书的CA，配置了证书、私钥或CA任意一项时使用TLS连接etcd",
+				Destination: &etcdConfig.TLS.CAFile,
+			},
+			&cli.StringFlag{
+				Name:        "etcd_username",
+				Usage:       "etcd开启RBAC时的用户名",
+				Destination: &etcdConfig.Username,
+			},
+			&cli.StringFlag{
+				Name:        "etcd_password",
+				Usage:       "etcd开启RBAC时的密码",
+				EnvVars:     []string{"MSNOWFLAKE_ETCD_PASSWORD"},
+				Destination: &etcdConfig.Password,
+			},
 			&cli.Int64Flag{
 				Name:        "msnowflake_worker_id",
-				Usage:       "workerId",
+				Usage:       "workerId，小于0时从etcd中自动选择空闲的workerId",
 				Value:       1,
 				Destination: &snowflakeConfig.WorkerId,
 			},
+			&cli.StringFlag{
+				Name:        "msnowflake_worker_assignment",
+				Usage:       "自动选择workerId的方式: leader(由选举出的leader统一分配)、lease(各节点自行抢占)",
+				Value:       basic.WorkerAssignmentLeader,
+				Destination: &snowflakeConfig.WorkerAssignment,
+			},
+			&cli.DurationFlag{
+				Name:        "msnowflake_worker_cooldown",
+				Usage:       "leader分配时workerId释放后的冷却时间，冷却期内不会分配给其他节点，以leader的配置为准",
+				Value:       time.Minute,
+				Destination: &snowflakeConfig.WorkerCooldown,
+			},
+			&cli.StringSliceFlag{
+				Name:  "msnowflake_worker_reserve",
+				Usage: "leader分配时保留给指定主机的workerId，格式为workerId=host，以leader的配置为准，可以指定多次",
+			},
+			&cli.DurationFlag{
+				Name:        "msnowflake_worker_assign_timeout",
+				Usage:       "等待leader分配workerId的时间",
+				Value:       basic.DefaultWorkerAssignTimeout,
+				Destination: &snowflakeConfig.WorkerAssignTimeout,
+			},
 			&cli.Int64Flag{
 				Name:        "msnowflake_datacenter",
 				Usage:       "workerId",
@@ -74,12 +168,121 @@ func main() {
 			},
 			&cli.StringFlag{
 				Name:        "msnowflake_twepoch",
-				Usage:       "twepoch",
-				Value:       "2020-02-02 13:14:52",
+				Usage:       "twepoch，RFC3339格式如2020-02-02T13:14:52Z，为空时使用layout默认的twepoch",
 				Destination: &snowflakeConfig.Twepoch,
 			},
+			&cli.StringFlag{
+				Name:        "msnowflake_twepoch_timezone",
+				Usage:       "twepoch为\"2006-01-02 15:04:05\"格式时使用的时区，如UTC、Asia/Shanghai",
+				Destination: &snowflakeConfig.TwepochTimezone,
+			},
+			&cli.DurationFlag{
+				Name:        "msnowflake_safety_margin",
+				Usage:       "timestamp耗尽前预留的时间，进入预留期后拒绝发号",
+				Value:       365 * 24 * time.Hour,
+				Destination: &snowflakeConfig.SafetyMargin,
+			},
+			&cli.DurationFlag{
+				Name:        "msnowflake_node_publish_interval",
+				Usage:       "向etcd公布节点信息的间隔，用于检测其他节点是否使用了相同的dataCenterId和workerId",
+				Value:       basic.DefaultNodePublishInterval,
+				Destination: &snowflakeConfig.NodePublishInterval,
+			},
+			&cli.StringFlag{
+				Name:        "msnowflake_layout",
+				Usage:       "id的位布局: twitter、sonyflake、instagram、discord，或timestamp,dataCenter,worker,sequence各字段位数",
+				Value:       model.DefaultLayout.String(),
+				Destination: &snowflakeConfig.Layout,
+			},
+			&cli.StringFlag{
+				Name:        "msnowflake_sequence_start",
+				Usage:       "每个时间单位的sequence起始值: zero、random、rotating，低流量时random和rotating让id低位分布均匀，但同一时间单位内的id不再单调递增",
+				Value:       model.SequenceStartZero.String(),
+				Destination: &snowflakeConfig.SequenceStart,
+			},
+			&cli.StringFlag{
+				Name:        "msnowflake_obfuscate_key",
+				Usage:       "混淆id的密钥(至少16字节)，为空时不混淆",
+				EnvVars:     []string{"MSNOWFLAKE_OBFUSCATE_KEY"},
+				Destination: &snowflakeConfig.ObfuscateKey,
+			},
+			&cli.StringFlag{
+				Name:        "msnowflake_journal_filename",
+				Usage:       "审计日志文件名，记录每次请求发出的id区间，为空时不记录",
+				Destination: &journalConfig.Filename,
+			},
+			&cli.IntFlag{
+				Name:        "msnowflake_journal_max_size",
+				Usage:       "审计日志文件大小(MB)",
+				Value:       200,
+				Destination: &journalConfig.MaxSize,
+			},
+			&cli.IntFlag{
+				Name:        "msnowflake_journal_max_age",
+				Usage:       "审计日志文件保存时间(day)",
+				Value:       90,
+				Destination: &journalConfig.MaxAge,
+			},
+			&cli.IntFlag{
+				Name:        "msnowflake_journal_max_backups",
+				Usage:       "审计日志文件最多保存多少个备份，为0时不限制",
+				Destination: &journalConfig.MaxBackups,
+			},
+			&cli.StringSliceFlag{
+				Name:  "msnowflake_limit",
+				Usage: "按调用方限流，格式为caller=rate:burst:dailyQuota，caller为*时作为默认配置，0表示不限制，限流时burst不能小于100，可以指定多次",
+			},
+			&cli.StringFlag{
+				Name:        "msnowflake_tls_cert",
+				Usage:       "TLS证书文件，作为客户端调用时为客户端证书",
+				Destination: &tlsConfig.CertFile,
+			},
+			&cli.StringFlag{
+				Name:        "msnowflake_tls_key",
+				Usage:       "TLS私钥文件",
+				Destination: &tlsConfig.KeyFile,
+			},
+			&cli.StringFlag{
+				Name:        "msnowflake_tls_ca",
+				Usage:       "CA证书文件，服务端配置后要求客户端提供该CA签发的证书(双向TLS)",
+				Destination: &tlsConfig.CAFile,
+			},
+			&cli.StringSliceFlag{
+				Name:    "msnowflake_auth_tokens",
+				Usage:   "服务端接受的token，格式为identity=token，可以指定多次。客户端通过auth_token参数携带token",
+				EnvVars: []string{"MSNOWFLAKE_AUTH_TOKENS"},
+			},
+			&cli.StringSliceFlag{
+				Name:  "msnowflake_auth_allow",
+				Usage: "允许访问的调用方身份(token的identity或客户端证书的CN)，为空时不限制，可以指定多次",
+			},
+			&cli.StringFlag{
+				Name:        "msnowflake_trace_endpoint",
+				Usage:       "OpenTelemetry trace上报地址(OTLP/HTTP)，如http://127.0.0.1:4318，为空时不上报",
+				EnvVars:     []string{"MSNOWFLAKE_TRACE_ENDPOINT"},
+				Destination: &traceConfig.Endpoint,
+			},
+			&cli.Float64Flag{
+				Name:        "msnowflake_trace_sample_ratio",
+				Usage:       "没有上游trace时的采样比例，有上游trace时跟随上游的采样结果",
+				Value:       1,
+				Destination: &traceConfig.SampleRatio,
+			},
+		),
+		micro.WrapHandler(
+			handler.TraceWrapper,
+			handler.RequestIdWrapper,
+			handler.RecoveryWrapper,
+			handler.AuthWrapper,
+			// 放在认证之后，日志中记录认证的调用方身份，认证失败由AuthWrapper记录
+			handler.LogWrapper,
+			handler.LimitWrapper,
 		),
 		micro.Action(func(c *cli.Context) error {
+			limitConfig.Limits = c.StringSlice("msnowflake_limit")
+			snowflakeConfig.WorkerReservations = c.StringSlice("msnowflake_worker_reserve")
+			authConfig.Tokens = c.StringSlice("msnowflake_auth_tokens")
+			authConfig.Allow = c.StringSlice("msnowflake_auth_allow")
 			etcdAddrs := c.String("etcd_address")
 			endpoints := strings.Split(etcdAddrs, ",")
 			etcdConfig.Endpoints = endpoints
@@ -87,30 +290,67 @@ func main() {
 			etcdConfig.ConnectTimeout = time.Duration(c.Int("etcd_connection_timeout")) * time.Second
 			return nil
 		}),
+		command.Commands(serviceName, &tlsConfig),
 	)
 
 	srv.Init(
 		micro.BeforeStart(func() (err error) {
 			basic.InitLog(logConfig)
+			handler.InitRequestLog(requestLogConfig)
+			if err = basic.InitTrace(traceConfig); err != nil {
+				return
+			}
+			if tlsConfig.Enabled() {
+				config, err := tlsConfig.ServerTLS()
+				if err != nil {
+					return err
+				}
+				if err = srv.Server().Init(grpcServer.AuthTLS(config)); err != nil {
+					return err
+				}
+			}
 			if err = basic.InitEtcd(etcdConfig); err != nil {
 				return
 			}
+			if _, err = model.InitJournal(journalConfig); err != nil {
+				return
+			}
 			if _, err = model.InitIdWorker(snowflakeConfig); err != nil {
 				return
 			}
+			if _, err = model.InitDetector(snowflakeConfig.GetNodePublishInterval()); err != nil {
+				return
+			}
+			if _, err = model.InitLimiter(limitConfig); err != nil {
+				return
+			}
 
 			if err = handler.Init(); err != nil {
 				return
 			}
+			if err = handler.InitAuth(authConfig); err != nil {
+				return
+			}
 			return nil
 		}),
 		micro.AfterStop(func() (err error) {
+			// 先释放workerId，不需要等待租约过期
+			if err := model.CloseIdWorker(); err != nil {
+				zap.S().Warnw("释放workerId失败", "err", err)
+			}
+			if err := basic.ShutdownTrace(); err != nil {
+				zap.S().Warnw("上报剩余的trace失败", "err", err)
+			}
 			err = zap.L().Sync()
 			basic.GetEtcd().Close()
 			return err
 		}),
 	)
 
+	if command.Executed() {
+		return
+	}
+
 	if err := msnowflake.RegisterMSnowflakeHandler(srv.Server(), new(handler.MSnowflake)); err != nil {
 		zap.S().Errorw("注册处理器失败", "err", err)
 		return
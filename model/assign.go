@@ -0,0 +1,437 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/LazzyQ/msnowflake/basic"
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+	"go.uber.org/zap"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 由选举出的leader统一分配workerId:
+// 节点在assign/request/下写入绑定自己租约的申请，leader选择workerId后在同一个事务中
+// 写入worker/<workerId>和assign/result/<nodeId>，两者都绑定申请者的租约，
+// 节点退出或租约失效后workerId自动释放。与自行抢占的节点共用worker/，互不重复。
+// 每次分配同时在assign/history/下写入不绑定租约的记录，节点退出后仍然可以审计，
+// 超过assignHistoryRetention的记录在之后的分配中删除。
+// key都在etcd配置的命名空间下
+const (
+	assignKeyPrefix      = "assign/"
+	assignLeaderKey      = assignKeyPrefix + "leader"
+	assignRequestPrefix  = assignKeyPrefix + "request/"
+	assignResultPrefix   = assignKeyPrefix + "result/"
+	assignCooldownPrefix = assignKeyPrefix + "cooldown/"
+	assignHistoryPrefix  = assignKeyPrefix + "history/"
+	assignLeaderTTL      = 5 // leader选举的租约时间(s)
+	assignAttempts       = 3 // 分配冲突时每个申请最多尝试的次数
+	// 分配记录保留的时间，避免频繁重启的集群中assign/history/无限增长
+	assignHistoryRetention = 30 * 24 * time.Hour
+	// leader定期重新检查未分配的申请，分配失败(如workerId被自行注册的节点抢占)后不需要等待新的事件
+	assignReconcileInterval = 5 * time.Second
+)
+
+// 节点申请workerId
+type AssignRequest struct {
+	NodeId       string    `json:"node_id"`
+	Host         string    `json:"host"`
+	DataCenterId int64     `json:"dc"`
+	MaxWorkerId  int64     `json:"max_worker"`
+	RequestTime  time.Time `json:"request_time"`
+}
+
+// leader分配的结果，同时作为审计记录写入assign/history/
+type Assignment struct {
+	NodeId     string    `json:"node_id"`
+	Host       string    `json:"host"`
+	WorkerId   int64     `json:"worker"`
+	AssignedBy string    `json:"assigned_by"`
+	AssignTime time.Time `json:"assign_time"`
+}
+
+// 解析workerId保留配置，格式为workerId=host
+func ParseReservation(s string) (int64, string, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || len(parts[1]) == 0 {
+		return 0, "", errors.New(fmt.Sprintf("workerId保留配置格式不正确: %s", s))
+	}
+	workerId, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || workerId < 0 {
+		return 0, "", errors.New(fmt.Sprintf("workerId保留配置的workerId不正确: %s", s))
+	}
+	return workerId, parts[1], nil
+}
+
+func ParseReservations(reservations []string) (map[int64]string, error) {
+	hosts := make(map[int64]string, len(reservations))
+	for _, s := range reservations {
+		workerId, host, err := ParseReservation(s)
+		if err != nil {
+			zap.S().Errorw("workerId保留配置不正确", "reservation", s, "err", err)
+			return nil, err
+		}
+		hosts[workerId] = host
+	}
+	return hosts, nil
+}
+
+// 优先选择保留给申请者主机的workerId，否则选择最小的没有被保留的空闲workerId，
+// 已被占用或在冷却期内的workerId不分配
+func chooseWorkerId(request AssignRequest, reservations map[int64]string, used, cooling map[int64]bool) (int64, bool) {
+	free := func(workerId int64) bool {
+		return workerId <= request.MaxWorkerId && !used[workerId] && !cooling[workerId]
+	}
+	reserved := make([]int64, 0)
+	for workerId, host := range reservations {
+		if host == request.Host && free(workerId) {
+			reserved = append(reserved, workerId)
+		}
+	}
+	if len(reserved) > 0 {
+		sort.Slice(reserved, func(i, j int) bool { return reserved[i] < reserved[j] })
+		return reserved[0], true
+	}
+	for workerId := int64(0); workerId <= request.MaxWorkerId; workerId++ {
+		if _, ok := reservations[workerId]; !ok && free(workerId) {
+			return workerId, true
+		}
+	}
+	return 0, false
+}
+
+// 向leader申请workerId，超过timeout没有分配时返回错误，注册失效后Done被关闭
+func RequestWorkerId(etcd *basic.Etcd, request AssignRequest, timeout time.Duration) (*Registration, error) {
+	key := assignRequestPrefix + request.NodeId
+	value, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := etcd.TxKeepaliveWithTTL(key, string(value), workerTTL)
+	if err != nil {
+		return nil, err
+	}
+	if !tx.Success {
+		zap.S().Errorw("申请workerId失败", "key", key, "value", tx.Value)
+		return nil, errors.New("申请workerId失败")
+	}
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), timeout)
+	defer cancelFunc()
+	assignment, err := waitAssignment(ctx, etcd, request.NodeId, tx.Done)
+	if err != nil {
+		zap.S().Errorw("等待leader分配workerId失败", "node", request.NodeId, "err", err)
+		_ = revoke(tx)
+		return nil, err
+	}
+	zap.S().Infow("leader分配workerId", "workerId", assignment.WorkerId, "node", request.NodeId, "leader", assignment.AssignedBy)
+	return newRegistration(etcd, assignment.WorkerId, tx), nil
+}
+
+func waitAssignment(ctx context.Context, etcd *basic.Etcd, nodeId string, done <-chan struct{}) (assignment Assignment, err error) {
+	key := assignResultPrefix + nodeId
+	// 先读取再从读取时的revision之后watch，不会漏掉两者之间的分配
+	kvs, revision, err := etcd.ListWithContext(ctx, key)
+	if err != nil {
+		return
+	}
+	for _, kv := range kvs {
+		if string(kv.Key) == key {
+			err = json.Unmarshal(kv.Value, &assignment)
+			return
+		}
+	}
+
+	watch := etcd.WatchWithContext(ctx, key, basic.WatchFromRevision(revision+1))
+	defer watch.CancelFunc()
+	for {
+		select {
+		case event, ok := <-watch.Event:
+			if !ok {
+				if ctx.Err() == context.DeadlineExceeded {
+					return assignment, errors.New("等待leader分配workerId超时")
+				}
+				return assignment, watch.Err()
+			}
+			if event.Key == key && event.Type != basic.KeyDeleteChangeEvent && event.Type != basic.KeyCompactedChangeEvent {
+				err = json.Unmarshal(event.Value, &assignment)
+				return
+			}
+		case <-done:
+			return assignment, errors.New("申请workerId的租约已失效")
+		}
+	}
+}
+
+// 参与leader选举，成为leader后为其他节点分配workerId
+type Assigner struct {
+	etcd         *basic.Etcd
+	nodeId       string
+	reservations map[int64]string
+	cooldown     time.Duration
+	cancelFunc   context.CancelFunc
+	wg           sync.WaitGroup
+}
+
+// 在后台参与选举，Close后退出选举，是leader时由其他节点接替
+func StartAssigner(etcd *basic.Etcd, nodeId string, reservations map[int64]string, cooldown time.Duration) *Assigner {
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	a := &Assigner{
+		etcd:         etcd,
+		nodeId:       nodeId,
+		reservations: reservations,
+		cooldown:     cooldown,
+		cancelFunc:   cancelFunc,
+	}
+	a.wg.Add(1)
+	go a.run(ctx)
+	return a
+}
+
+func (a *Assigner) Close() {
+	a.cancelFunc()
+	a.wg.Wait()
+}
+
+func (a *Assigner) run(ctx context.Context) {
+	defer a.wg.Done()
+	for ctx.Err() == nil {
+		if err := a.campaign(ctx); err != nil && ctx.Err() == nil {
+			zap.S().Warnw("workerId分配的leader选举失败，稍后重试", "node", a.nodeId, "err", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+		}
+	}
+}
+
+// 成为leader后分配workerId，直到失去leader身份或ctx结束
+func (a *Assigner) campaign(ctx context.Context) error {
+	election, err := a.etcd.NewElection(assignLeaderKey, assignLeaderTTL)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = election.Close() }()
+	if err = election.Campaign(ctx, a.nodeId); err != nil {
+		return err
+	}
+
+	zap.S().Infow("成为workerId分配的leader", "node", a.nodeId)
+	defer zap.S().Infow("不再是workerId分配的leader", "node", a.nodeId)
+	ctx, cancelFunc := context.WithCancel(ctx)
+	defer cancelFunc()
+	return a.lead(ctx, election.Done())
+}
+
+func (a *Assigner) lead(ctx context.Context, lost <-chan struct{}) error {
+	requests := a.etcd.WatchWithContext(ctx, assignRequestPrefix, basic.WatchPrefix())
+	workers := a.etcd.WatchWithContext(ctx, workerKeyPrefix, basic.WatchPrefix())
+	ticker := time.NewTicker(assignReconcileInterval)
+	defer ticker.Stop()
+
+	// 上一次检查时已被占用的workerId，刚成为leader时为nil
+	known := a.reconcile(ctx, nil)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-lost:
+			return errors.New("leader选举的租约已失效")
+		case event, ok := <-requests.Event:
+			if !ok {
+				return requests.Err()
+			}
+			if event.Type != basic.KeyDeleteChangeEvent {
+				known = a.reconcile(ctx, known)
+			}
+		case event, ok := <-workers.Event:
+			if !ok {
+				return workers.Err()
+			}
+			// 释放后及时记录冷却期
+			if event.Type == basic.KeyDeleteChangeEvent {
+				known = a.reconcile(ctx, known)
+			}
+		case <-ticker.C:
+			known = a.reconcile(ctx, known)
+		}
+	}
+}
+
+// 为所有还没有分配的申请分配workerId，返回本次检查后已被占用的workerId。
+// 上一次检查时被占用、本次已经释放的workerId先进入冷却期再分配，不依赖是否已经收到释放事件
+func (a *Assigner) reconcile(ctx context.Context, known map[int64]bool) map[int64]bool {
+	requests, _, err := a.etcd.ListWithContext(ctx, assignRequestPrefix)
+	if err != nil {
+		zap.S().Warnw("读取workerId申请失败", "err", err)
+		return known
+	}
+	assigned, used, cooling, err := a.state(ctx)
+	if err != nil {
+		zap.S().Warnw("读取workerId分配情况失败", "err", err)
+		return known
+	}
+	var failed []int64
+	for workerId := range known {
+		if used[workerId] {
+			continue
+		}
+		cooling[workerId] = true
+		if err := a.startCooldown(ctx, workerId); err != nil {
+			zap.S().Warnw("记录workerId冷却期失败", "workerId", workerId, "err", err)
+			failed = append(failed, workerId)
+		}
+	}
+
+	// 按申请的先后顺序分配
+	sort.Slice(requests, func(i, j int) bool { return requests[i].CreateRevision < requests[j].CreateRevision })
+	for _, kv := range requests {
+		var request AssignRequest
+		if err := json.Unmarshal(kv.Value, &request); err != nil || kv.Lease == 0 {
+			zap.S().Warnw("workerId申请格式不正确", "key", string(kv.Key), "value", string(kv.Value), "err", err)
+			continue
+		}
+		if assigned[request.NodeId] {
+			continue
+		}
+		for attempt := 0; attempt < assignAttempts; attempt++ {
+			workerId, ok := chooseWorkerId(request, a.reservations, used, cooling)
+			if !ok {
+				zap.S().Warnw("没有可以分配的workerId", "node", request.NodeId, "host", request.Host, "maxWorkerId", request.MaxWorkerId)
+				break
+			}
+			err := a.assign(ctx, kv, request, workerId)
+			if basic.IsConflict(err) {
+				// workerId刚被自行注册的节点占用或进入冷却期，也可能申请已被撤销，换一个workerId重试，
+				// 仍然失败时等待下一次检查
+				used[workerId] = true
+				continue
+			}
+			if err != nil {
+				zap.S().Warnw("分配workerId失败", "node", request.NodeId, "workerId", workerId, "err", err)
+			} else {
+				used[workerId] = true
+			}
+			break
+		}
+	}
+	// 记录冷却期失败的workerId在下一次检查时重试
+	for _, workerId := range failed {
+		used[workerId] = true
+	}
+	return used
+}
+
+// 记录workerId的冷却期，冷却期记录在etcd中，leader切换后仍然有效。
+// 没有leader期间释放的workerId不会进入冷却期
+func (a *Assigner) startCooldown(ctx context.Context, workerId int64) error {
+	if a.cooldown <= 0 {
+		return nil
+	}
+	ttl := int64(math.Ceil(a.cooldown.Seconds()))
+	tx, err := a.etcd.TxWithTTLWithContext(ctx, cooldownKey(workerId), time.Now().Format(time.RFC3339Nano), ttl)
+	if err != nil {
+		return err
+	}
+	if tx.Success {
+		_ = tx.Lease.Close()
+		zap.S().Infow("workerId已释放，进入冷却期", "workerId", workerId, "cooldown", a.cooldown)
+	}
+	return nil
+}
+
+// 已分配的节点、已被占用的workerId和冷却期内的workerId
+func (a *Assigner) state(ctx context.Context) (assigned map[string]bool, used, cooling map[int64]bool, err error) {
+	results, _, err := a.etcd.ListWithContext(ctx, assignResultPrefix)
+	if err != nil {
+		return
+	}
+	workers, _, err := a.etcd.ListWithContext(ctx, workerKeyPrefix)
+	if err != nil {
+		return
+	}
+	cooldowns, _, err := a.etcd.ListWithContext(ctx, assignCooldownPrefix)
+	if err != nil {
+		return
+	}
+	assigned = make(map[string]bool, len(results))
+	for _, kv := range results {
+		assigned[strings.TrimPrefix(string(kv.Key), assignResultPrefix)] = true
+	}
+	used = workerIds(workers, workerKeyPrefix)
+	cooling = workerIds(cooldowns, assignCooldownPrefix)
+	return
+}
+
+func workerIds(kvs []*mvccpb.KeyValue, prefix string) map[int64]bool {
+	ids := make(map[int64]bool, len(kvs))
+	for _, kv := range kvs {
+		if workerId, err := strconv.ParseInt(strings.TrimPrefix(string(kv.Key), prefix), 10, 64); err == nil {
+			ids[workerId] = true
+		}
+	}
+	return ids
+}
+
+// 申请没有变化、workerId没有被占用也不在冷却期时，写入workerId和分配结果，都绑定申请者的租约，
+// 同时写入不绑定租约的分配记录
+func (a *Assigner) assign(ctx context.Context, kv *mvccpb.KeyValue, request AssignRequest, workerId int64) error {
+	assignment := Assignment{
+		NodeId:     request.NodeId,
+		Host:       request.Host,
+		WorkerId:   workerId,
+		AssignedBy: a.nodeId,
+		AssignTime: time.Now(),
+	}
+	value, err := json.Marshal(assignment)
+	if err != nil {
+		return err
+	}
+	resultKey := assignResultPrefix + request.NodeId
+	// 按分配时间排序，节点标识保证不重复
+	historyKey := historyKeyAt(assignment.AssignTime) + "-" + request.NodeId
+	expired := historyKeyAt(assignment.AssignTime.Add(-assignHistoryRetention))
+	lease := clientv3.WithLease(clientv3.LeaseID(kv.Lease))
+	_, err = a.etcd.TxnWithContext(ctx,
+		[]clientv3.Cmp{
+			clientv3.Compare(clientv3.ModRevision(string(kv.Key)), "=", kv.ModRevision),
+			clientv3.Compare(clientv3.Version(workerKey(workerId)), "=", 0),
+			clientv3.Compare(clientv3.Version(cooldownKey(workerId)), "=", 0),
+			clientv3.Compare(clientv3.Version(resultKey), "=", 0),
+		},
+		[]clientv3.Op{
+			clientv3.OpPut(workerKey(workerId), strconv.FormatInt(workerId, 10), lease),
+			clientv3.OpPut(resultKey, string(value), lease),
+			clientv3.OpPut(historyKey, string(value)),
+			// 同一个事务中删除过期的记录，key按时间排序，删除cutoff之前的区间即可
+			clientv3.OpDelete(assignHistoryPrefix, clientv3.WithRange(expired)),
+		})
+	if err != nil {
+		return err
+	}
+	zap.S().Infow("分配workerId",
+		"workerId", workerId,
+		"node", request.NodeId,
+		"host", request.Host,
+		"dataCenterId", request.DataCenterId,
+		"leader", a.nodeId,
+		"reserved", a.reservations[workerId] == request.Host)
+	return nil
+}
+
+// 分配时间为t的记录的key前缀，早于t的记录的key都小于它
+func historyKeyAt(t time.Time) string {
+	return fmt.Sprintf("%s%019d", assignHistoryPrefix, t.UnixNano())
+}
+
+func cooldownKey(workerId int64) string {
+	return assignCooldownPrefix + strconv.FormatInt(workerId, 10)
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestParseReservation(t *testing.T) {
+	workerId, host, err := ParseReservation("7=snowflake-1")
+	if err != nil || workerId != 7 || host != "snowflake-1" {
+		t.Fatalf("workerId:%d host:%s err:%v", workerId, host, err)
+	}
+	for _, s := range []string{"", "7", "7=", "x=a", "-1=a"} {
+		if _, _, err := ParseReservation(s); err == nil {
+			t.Errorf("%q 应该解析失败", s)
+		}
+	}
+}
+
+func TestChooseWorkerId(t *testing.T) {
+	reservations := map[int64]string{0: "a", 2: "a", 3: "b"}
+	request := AssignRequest{Host: "a", MaxWorkerId: 5}
+	ids := func(workerIds ...int64) map[int64]bool {
+		m := make(map[int64]bool)
+		for _, workerId := range workerIds {
+			m[workerId] = true
+		}
+		return m
+	}
+
+	cases := []struct {
+		host     string
+		used     map[int64]bool
+		cooling  map[int64]bool
+		workerId int64
+		ok       bool
+	}{
+		// 优先分配保留给本主机的最小workerId
+		{"a", nil, nil, 0, true},
+		{"a", ids(0), nil, 2, true},
+		// 保留的workerId都不可用时分配没有保留的workerId
+		{"a", ids(0), ids(2), 1, true},
+		// 不分配保留给其他主机的workerId
+		{"c", nil, nil, 1, true},
+		{"c", ids(1), ids(4), 5, true},
+		{"c", ids(1, 4, 5), nil, 0, false},
+	}
+	for _, c := range cases {
+		request.Host = c.host
+		workerId, ok := chooseWorkerId(request, reservations, c.used, c.cooling)
+		if workerId != c.workerId || ok != c.ok {
+			t.Errorf("host:%s used:%v cooling:%v workerId:%d ok:%v", c.host, c.used, c.cooling, workerId, ok)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"sync"
+	"time"
+)
+
+// 时钟接口，IdWorker通过它获取当前时间和等待时钟前进
+type Clock interface {
+	Now() time.Time
+	// 返回一个在d之后触发的channel
+	After(d time.Duration) <-chan time.Time
+}
+
+// 系统时钟
+type systemClock struct {
+}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// 可手动控制的时钟，用于测试时钟回拨、sequence耗尽等场景
+type FakeClock struct {
+	now   time.Time
+	mutex sync.Mutex
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// 不会真正等待，直接把时钟拨到d之后并立即触发
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if d > 0 {
+		c.now = c.now.Add(d)
+	}
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+// 把时钟设置为指定时间，可以早于当前时间以模拟时钟回拨
+func (c *FakeClock) Set(now time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = now
+}
+
+// 时钟前进d，d为负数时表示回拨
+func (c *FakeClock) Add(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
@@ -0,0 +1,147 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// id的字符串编码，取值与proto中的Encoding一致
+type Encoding int32
+
+const (
+	EncodingNone Encoding = iota
+	EncodingBase62
+	EncodingCrockfordBase32
+	EncodingHex
+	EncodingDecimal
+)
+
+const (
+	base62Alphabet          = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	crockfordBase32Alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	hexAlphabet             = "0123456789abcdef"
+	decimalAlphabet         = "0123456789"
+)
+
+type codec struct {
+	alphabet string
+	width    int // 编码后的定长，不足时左边补0，保证字典序与数值大小一致
+	decode   [256]int8
+}
+
+var codecs = map[Encoding]*codec{
+	EncodingBase62:          newCodec(base62Alphabet),
+	EncodingCrockfordBase32: newCodec(crockfordBase32Alphabet),
+	EncodingHex:             newCodec(hexAlphabet),
+	EncodingDecimal:         newCodec(decimalAlphabet),
+}
+
+func init() {
+	// crockford base32解码时不区分大小写，I、L当作1，O当作0
+	c := codecs[EncodingCrockfordBase32]
+	for i := 0; i < len(crockfordBase32Alphabet); i++ {
+		c.decode[strings.ToLower(crockfordBase32Alphabet)[i]] = int8(i)
+	}
+	for _, ch := range "IiLl" {
+		c.decode[ch] = 1
+	}
+	for _, ch := range "Oo" {
+		c.decode[ch] = 0
+	}
+	// hex解码时不区分大小写
+	h := codecs[EncodingHex]
+	for i := 10; i < len(hexAlphabet); i++ {
+		h.decode[strings.ToUpper(hexAlphabet)[i]] = int8(i)
+	}
+}
+
+func newCodec(alphabet string) *codec {
+	c := &codec{alphabet: alphabet}
+	for i := range c.decode {
+		c.decode[i] = -1
+	}
+	for i := 0; i < len(alphabet); i++ {
+		c.decode[alphabet[i]] = int8(i)
+	}
+	for n := uint64(math.MaxInt64); n > 0; n /= uint64(len(alphabet)) {
+		c.width++
+	}
+	return c
+}
+
+func (e Encoding) String() string {
+	switch e {
+	case EncodingNone:
+		return "none"
+	case EncodingBase62:
+		return "base62"
+	case EncodingCrockfordBase32:
+		return "crockford_base32"
+	case EncodingHex:
+		return "hex"
+	case EncodingDecimal:
+		return "decimal"
+	default:
+		return fmt.Sprintf("Encoding(%d)", int32(e))
+	}
+}
+
+// 根据名称获取编码，名称不区分大小写
+func ParseEncoding(name string) (Encoding, error) {
+	for e := EncodingNone; e <= EncodingDecimal; e++ {
+		if strings.EqualFold(name, e.String()) {
+			return e, nil
+		}
+	}
+	return EncodingNone, errors.New(fmt.Sprintf("不支持的编码: %s", name))
+}
+
+// 把id编码为定长字符串
+func Encode(id int64, encoding Encoding) (string, error) {
+	c, ok := codecs[encoding]
+	if !ok {
+		return "", errors.New(fmt.Sprintf("不支持的编码: %s", encoding))
+	}
+	if id < 0 {
+		return "", errors.New(fmt.Sprintf("id不能为负数: %d", id))
+	}
+
+	base := uint64(len(c.alphabet))
+	buf := make([]byte, c.width)
+	n := uint64(id)
+	for i := len(buf) - 1; i >= 0; i-- {
+		buf[i] = c.alphabet[n%base]
+		n /= base
+	}
+	return string(buf), nil
+}
+
+// 把Encode得到的字符串还原为id，可以省略前导0，crockford base32可以带'-'分隔符
+func Decode(s string, encoding Encoding) (int64, error) {
+	c, ok := codecs[encoding]
+	if !ok {
+		return 0, errors.New(fmt.Sprintf("不支持的编码: %s", encoding))
+	}
+	if encoding == EncodingCrockfordBase32 {
+		s = strings.Replace(s, "-", "", -1)
+	}
+	if len(s) == 0 {
+		return 0, errors.New("id字符串不能为空")
+	}
+
+	base := uint64(len(c.alphabet))
+	var n uint64
+	for i := 0; i < len(s); i++ {
+		d := c.decode[s[i]]
+		if d < 0 {
+			return 0, errors.New(fmt.Sprintf("id字符串包含非法字符: %s", s))
+		}
+		if n > (math.MaxInt64-uint64(d))/base {
+			return 0, errors.New(fmt.Sprintf("id字符串超出范围: %s", s))
+		}
+		n = n*base + uint64(d)
+	}
+	return int64(n), nil
+}
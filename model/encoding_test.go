@@ -0,0 +1,121 @@
+package model
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	cases := []struct {
+		id       int64
+		encoding Encoding
+		expected string
+	}{
+		{0, EncodingBase62, "00000000000"},
+		{61, EncodingBase62, "0000000000z"},
+		{math.MaxInt64, EncodingBase62, "AzL8n0Y58m7"},
+		{31, EncodingCrockfordBase32, "000000000000Z"},
+		{math.MaxInt64, EncodingCrockfordBase32, "7ZZZZZZZZZZZZ"},
+		{255, EncodingHex, "00000000000000ff"},
+		{42, EncodingDecimal, "0000000000000000042"},
+	}
+	for _, c := range cases {
+		s, err := Encode(c.id, c.encoding)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != c.expected {
+			t.Errorf("%s编码%d结果不正确, expected:%s, actual:%s", c.encoding, c.id, c.expected, s)
+		}
+	}
+
+	if _, err := Encode(-1, EncodingBase62); err == nil {
+		t.Error("负数应该返回错误")
+	}
+	if _, err := Encode(1, EncodingNone); err == nil {
+		t.Error("NONE编码应该返回错误")
+	}
+}
+
+func TestDecode(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for e := EncodingBase62; e <= EncodingDecimal; e++ {
+		ids := []int64{0, 1, math.MaxInt64}
+		for i := 0; i < 1000; i++ {
+			ids = append(ids, rnd.Int63())
+		}
+		strs := make([]string, len(ids))
+		for i, id := range ids {
+			s, err := Encode(id, e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := Decode(s, e)
+			if err != nil || decoded != id {
+				t.Fatalf("%s解码结果不正确, id:%d, s:%s, decoded:%d, err:%v", e, id, s, decoded, err)
+			}
+			strs[i] = s
+		}
+
+		// 字典序应该与数值大小一致
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		sort.Strings(strs)
+		for i, s := range strs {
+			if decoded, _ := Decode(s, e); decoded != ids[i] {
+				t.Fatalf("%s编码的字典序与数值大小不一致, s:%s", e, s)
+			}
+		}
+	}
+}
+
+func TestDecode_Lenient(t *testing.T) {
+	cases := []struct {
+		s        string
+		encoding Encoding
+		expected int64
+	}{
+		{"z", EncodingBase62, 61},
+		{"0000-0000-000z", EncodingCrockfordBase32, 31},
+		{"ilo", EncodingCrockfordBase32, 1<<5 + 1<<10},
+		{"FF", EncodingHex, 255},
+	}
+	for _, c := range cases {
+		id, err := Decode(c.s, c.encoding)
+		if err != nil || id != c.expected {
+			t.Errorf("%s解码%s结果不正确, expected:%d, actual:%d, err:%v", c.encoding, c.s, c.expected, id, err)
+		}
+	}
+
+	for _, c := range []struct {
+		s        string
+		encoding Encoding
+	}{
+		{"", EncodingBase62},
+		{"AzL8n0Y58m8", EncodingBase62},
+		{"8000000000000", EncodingCrockfordBase32},
+		{"U", EncodingCrockfordBase32},
+		{"8000000000000000", EncodingHex},
+		{"9223372036854775808", EncodingDecimal},
+		{"-1", EncodingDecimal},
+	} {
+		if _, err := Decode(c.s, c.encoding); err == nil {
+			t.Errorf("%s解码%q应该返回错误", c.encoding, c.s)
+		}
+	}
+}
+
+func TestParseEncoding(t *testing.T) {
+	for e := EncodingNone; e <= EncodingDecimal; e++ {
+		if parsed, err := ParseEncoding(e.String()); err != nil || parsed != e {
+			t.Errorf("解析编码名称失败: %s", e)
+		}
+	}
+	if e, err := ParseEncoding("BASE62"); err != nil || e != EncodingBase62 {
+		t.Error("编码名称应该不区分大小写")
+	}
+	if _, err := ParseEncoding("base64"); err == nil {
+		t.Error("不支持的编码应该返回错误")
+	}
+}
@@ -1,13 +1,15 @@
 package model
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"github.com/LazzyQ/msnowflake/basic"
 	"go.uber.org/zap"
 	"time"
 )
 
-// id = [timestamp][dataCenterId:5][workerId:5][sequence:12]
+// 默认布局(Twitter): id = [timestamp][dataCenterId:5][workerId:5][sequence:12]
 const (
 	workerIdBits       = uint(5)
 	dataCenterIdBits   = uint(5)
@@ -18,67 +20,212 @@ const (
 	dataCenterIdShift  = sequenceBits + workerIdBits
 	timestampLeftShift = sequenceBits + workerIdBits + dataCenterIdBits
 	sequenceMask       = -1 ^ (-1 << sequenceBits)
-	maxNextIdsNum      = 100
+	MaxNextIdsNum      = 100 // 单次批量获取的最大数量
+	maxBackwardMillis  = 5   // 可容忍的时钟回拨(ms)，超过则直接拒绝
 )
 
 func (id *IdWorker) NextId() (int64, error) {
-	id.mutex.Lock()
-	defer id.mutex.Unlock()
-	timestamp := timeGen()
-	if timestamp < id.lastTimestamp {
-		zap.S().Errorf("时钟回调. 请求拒绝%dms, timestamp:%v,lastTimestamp:%v", id.lastTimestamp-timestamp, timestamp, id.lastTimestamp)
-		return 0, errors.New(fmt.Sprintf("时钟回调. 请求拒绝%dms", id.lastTimestamp-timestamp))
+	return id.NextIdWithContext(context.Background())
+}
+
+// 获取一个id，ctx取消或超时后不再等待
+func (id *IdWorker) NextIdWithContext(ctx context.Context) (_ int64, err error) {
+	ctx, span := id.startSpan(ctx, "NextId", 1)
+	defer func() { basic.EndSpan(span, err) }()
+	if err := ctx.Err(); err != nil {
+		return 0, err
 	}
-	if id.lastTimestamp == timestamp {
-		id.sequence = (id.sequence + 1) & sequenceMask
-		if id.sequence == 0 {
-			timestamp = tilNextMillis(id.lastTimestamp)
-		}
-	} else {
-		id.sequence = 0
+	id.mutex.Lock()
+	v, err := id.nextId(ctx)
+	pos := id.position()
+	id.mutex.Unlock()
+	if err != nil {
+		return 0, err
 	}
-	id.lastTimestamp = timestamp
-	return ((timestamp - id.twepoch) << timestampLeftShift) | (id.dataCenterId << dataCenterIdShift) | (id.workerId << workerIdShift) | id.sequence, nil
+	id.writeJournal(ctx, pos, pos, 1)
+	return id.obfuscate(v)
 }
 
 func (id *IdWorker) NextIds(num uint32) ([]int64, error) {
-	if num > maxNextIdsNum || num < 0 {
-		zap.S().Errorf("取id超过NextIds限制的数量或小于0, maxIdNum:%v, currentIdNum:%v", maxNextIdsNum, num)
+	return id.NextIdsWithContext(context.Background(), num)
+}
+
+// 批量获取id，ctx取消或超时后不再等待，已生成的id也不会返回
+func (id *IdWorker) NextIdsWithContext(ctx context.Context, num uint32) (_ []int64, err error) {
+	ctx, span := id.startSpan(ctx, "NextIds", num)
+	defer func() { basic.EndSpan(span, err) }()
+	if num > MaxNextIdsNum || num < 0 {
+		zap.S().Errorf("取id超过NextIds限制的数量或小于0, maxIdNum:%v, currentIdNum:%v", MaxNextIdsNum, num)
 		return nil, errors.New(fmt.Sprintf("NextIds数量参数不对: %d", num))
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	ids := make([]int64, num)
+	var (
+		i           uint32
+		first, last idPosition
+	)
 	id.mutex.Lock()
-	defer id.mutex.Unlock()
-	var i uint32
 	for i = 0; i < num; i++ {
-		timestamp := timeGen()
-		if timestamp < id.lastTimestamp {
-			zap.S().Errorf("时钟回调. 请求拒绝%dms, timestamp:%v,lastTimestamp:%v", id.lastTimestamp-timestamp, timestamp, id.lastTimestamp)
-			return nil, errors.New(fmt.Sprintf("时钟回调. 请求拒绝%dms", id.lastTimestamp-timestamp))
+		if ids[i], err = id.nextId(ctx); err != nil {
+			break
 		}
-		if id.lastTimestamp == timestamp {
-			id.sequence = (id.sequence + 1) & sequenceMask
-			if id.sequence == 0 {
-				timestamp = tilNextMillis(id.lastTimestamp)
-			}
-		} else {
-			id.sequence = 0
+		if i == 0 {
+			first = id.position()
+		}
+	}
+	last = id.position()
+	id.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if num > 0 {
+		id.writeJournal(ctx, first, last, int(num))
+	}
+	for i = 0; i < num; i++ {
+		if ids[i], err = id.obfuscate(ids[i]); err != nil {
+			return nil, err
 		}
-		id.lastTimestamp = timestamp
-		ids[i] = ((timestamp - id.twepoch) << timestampLeftShift) | (id.dataCenterId << dataCenterIdShift) | (id.workerId << workerIdShift) | id.sequence
 	}
 	return ids, nil
 }
 
-// 返回的是当前时间戳，但是是ms
-func timeGen() int64 {
-	return time.Now().UnixNano() / int64(time.Millisecond)
+// id中的timestamp和sequence字段，以及该时间单位的sequence起始值
+type idPosition struct {
+	timestamp int64
+	sequence  int64
+	offset    int64
+}
+
+// 最近一次发出的id的位置，调用方必须持有mutex
+func (id *IdWorker) position() idPosition {
+	return idPosition{timestamp: id.lastTimestamp - id.twepoch, sequence: id.sequence, offset: id.sequenceOffset}
+}
+
+// 把[first, last]区间写入审计日志，写入失败不影响发号
+func (id *IdWorker) writeJournal(ctx context.Context, first, last idPosition, count int) {
+	if id.journal == nil {
+		return
+	}
+	info, _ := RequestInfoFromContext(ctx)
+	record := JournalRecord{
+		Time:          id.clock.Now(),
+		DataCenterId:  id.dataCenterId,
+		WorkerId:      id.workerId,
+		TimestampFrom: first.timestamp,
+		SequenceFrom:  first.sequence,
+		TimestampTo:   last.timestamp,
+		SequenceTo:    last.sequence,
+		OffsetFrom:    first.offset,
+		OffsetTo:      last.offset,
+		Count:         count,
+		Caller:        info.Caller,
+		RequestId:     info.RequestId,
+	}
+	if err := id.journal.Write(record); err != nil {
+		zap.S().Errorw("写入审计日志失败", "record", record, "err", err)
+	}
+}
+
+// 开启混淆时对生成的id做置换，不需要持有mutex
+func (id *IdWorker) obfuscate(v int64) (int64, error) {
+	if id.obfuscator == nil {
+		return v, nil
+	}
+	return id.obfuscator.Obfuscate(v)
+}
+
+// 还原混淆后的id，未开启混淆时返回错误
+func (id *IdWorker) Reveal(v int64) (int64, error) {
+	if id.obfuscator == nil {
+		return 0, errors.New("未开启id混淆")
+	}
+	return id.obfuscator.Reveal(v)
 }
 
-func tilNextMillis(lastTimestamp int64) int64 {
-	timestamp := timeGen()
-	for timestamp <= lastTimestamp {
-		timestamp = timeGen()
+// 生成一个id，调用方必须持有mutex
+func (id *IdWorker) nextId(ctx context.Context) (int64, error) {
+	if len(id.disabled) > 0 {
+		return 0, id.disabled[0]
+	}
+	unit := id.layout.TimeUnit
+	timestamp, err := id.timeGenSince(ctx, id.lastTimestamp, unit)
+	if err != nil {
+		return 0, err
+	}
+	if err = id.checkTimestamp(timestamp); err != nil {
+		return 0, err
+	}
+	maxSequence := id.layout.MaxSequence()
+	if id.lastTimestamp == timestamp {
+		id.sequence = (id.sequence + 1) & maxSequence
+		if id.sequence != id.sequenceOffset {
+			return id.layout.compose(timestamp-id.twepoch, id.dataCenterId, id.workerId, id.sequence), nil
+		}
+		// sequence回到起始值说明当前时间单位已经用完，等待进入下一个时间单位
+		traceSequenceExhausted(ctx)
+		if timestamp, err = id.waitUntil(ctx, id.lastTimestamp+1, unit); err == nil {
+			err = id.checkTimestamp(timestamp)
+		}
+		if err != nil {
+			// 保持耗尽状态，避免下次调用重复发号
+			id.sequence = (id.sequenceOffset - 1) & maxSequence
+			return 0, err
+		}
+	}
+	id.sequenceOffset = id.nextSequenceOffset()
+	id.sequence = id.sequenceOffset
+	id.lastTimestamp = timestamp
+	if id.firstTimestamp < 0 {
+		id.firstTimestamp = timestamp
+	}
+	return id.layout.compose(timestamp-id.twepoch, id.dataCenterId, id.workerId, id.sequence), nil
+}
+
+// timestamp不能早于twepoch，也不能进入耗尽前的安全预留期
+func (id *IdWorker) checkTimestamp(timestamp int64) error {
+	if elapsed := timestamp - id.twepoch; elapsed < 0 || elapsed > id.maxTimestamp {
+		zap.S().Errorw("timestamp超出可用范围，拒绝发号", "timestamp", timestamp, "twepoch", id.twepoch, "maxTimestamp", id.maxTimestamp)
+		return errors.New("timestamp超出可用范围")
+	}
+	return nil
+}
+
+// 返回当前时间戳，单位为unit
+func (id *IdWorker) timeGen(unit time.Duration) int64 {
+	return id.clock.Now().UnixNano() / int64(unit)
+}
+
+// 返回不早于lastTimestamp的当前时间戳，时钟小幅回拨时等待追上，大幅回拨时拒绝
+func (id *IdWorker) timeGenSince(ctx context.Context, lastTimestamp int64, unit time.Duration) (int64, error) {
+	timestamp := id.timeGen(unit)
+	if timestamp >= lastTimestamp {
+		return timestamp, nil
+	}
+	// 时间单位大于可容忍的回拨时，回退一个时间单位也需要等待
+	offset := time.Duration(lastTimestamp-timestamp) * unit
+	if offset > maxBackwardMillis*time.Millisecond && offset > unit {
+		zap.S().Errorf("时钟回调. 请求拒绝%dms, timestamp:%v,lastTimestamp:%v", offset/time.Millisecond, timestamp, lastTimestamp)
+		traceClockRollback(ctx, offset, true)
+		return 0, errors.New(fmt.Sprintf("时钟回调. 请求拒绝%dms", offset/time.Millisecond))
+	}
+	zap.S().Warnf("时钟回调. 等待%dms, timestamp:%v,lastTimestamp:%v", offset/time.Millisecond, timestamp, lastTimestamp)
+	traceClockRollback(ctx, offset, false)
+	return id.waitUntil(ctx, lastTimestamp, unit)
+}
+
+// 等待直到时钟不小于指定时间戳，时间戳的单位为unit
+func (id *IdWorker) waitUntil(ctx context.Context, timestamp int64, unit time.Duration) (int64, error) {
+	now := id.timeGen(unit)
+	for now < timestamp {
+		d := time.Unix(0, timestamp*int64(unit)).Sub(id.clock.Now())
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-id.clock.After(d):
+		}
+		now = id.timeGen(unit)
 	}
-	return timestamp
+	return now, nil
 }
@@ -0,0 +1,382 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var testTwepoch = time.Date(2020, 2, 2, 13, 14, 52, 0, time.UTC)
+
+func newTestIdWorker(t *testing.T, clock Clock) *IdWorker {
+	idWorker, err := NewIdWorker(3, 7, testTwepoch, WithClock(clock))
+	if err != nil {
+		t.Fatal("创建IdWorker失败", err)
+	}
+	return idWorker
+}
+
+// 拆分id为timestamp、dataCenterId、workerId、sequence
+func splitId(id int64) (timestamp, dataCenterId, workerId, sequence int64) {
+	timestamp = id >> timestampLeftShift
+	dataCenterId = (id >> dataCenterIdShift) & maxDataCenterId
+	workerId = (id >> workerIdShift) & maxWorkerId
+	sequence = id & sequenceMask
+	return
+}
+
+func TestNewIdWorker(t *testing.T) {
+	if _, err := NewIdWorker(0, maxWorkerId+1, testTwepoch); err == nil {
+		t.Error("workerId超过限制时应该返回错误")
+	}
+	if _, err := NewIdWorker(-1, 0, testTwepoch); err == nil {
+		t.Error("dataCenterId小于0时应该返回错误")
+	}
+	if _, err := NewIdWorker(maxDataCenterId, maxWorkerId, testTwepoch); err != nil {
+		t.Error("边界值应该合法", err)
+	}
+}
+
+func TestIdWorker_NextId(t *testing.T) {
+	clock := NewFakeClock(testTwepoch.Add(1234 * time.Millisecond))
+	idWorker := newTestIdWorker(t, clock)
+
+	id, err := idWorker.NextId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	timestamp, dataCenterId, workerId, sequence := splitId(id)
+	if timestamp != 1234 || dataCenterId != 3 || workerId != 7 || sequence != 0 {
+		t.Errorf("id字段不正确, timestamp:%d, dataCenterId:%d, workerId:%d, sequence:%d", timestamp, dataCenterId, workerId, sequence)
+	}
+
+	id2, err := idWorker.NextId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, sequence = splitId(id2); sequence != 1 || id2 <= id {
+		t.Errorf("同一毫秒内sequence应该递增, id:%d, id2:%d", id, id2)
+	}
+
+	clock.Add(time.Millisecond)
+	id3, err := idWorker.NextId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timestamp, _, _, sequence = splitId(id3); timestamp != 1235 || sequence != 0 {
+		t.Errorf("进入下一毫秒后sequence应该重置, timestamp:%d, sequence:%d", timestamp, sequence)
+	}
+}
+
+func TestIdWorker_NextIdAtEpoch(t *testing.T) {
+	idWorker := newTestIdWorker(t, NewFakeClock(testTwepoch))
+
+	id, err := idWorker.NextId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 3<<dataCenterIdShift|7<<workerIdShift {
+		t.Errorf("twepoch时刻生成的id只包含dataCenterId和workerId, id:%d", id)
+	}
+}
+
+func TestIdWorker_ClockRollback(t *testing.T) {
+	clock := NewFakeClock(testTwepoch.Add(time.Second))
+	idWorker := newTestIdWorker(t, clock)
+
+	id, err := idWorker.NextId()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 小幅回拨会等待时钟追上
+	clock.Add(-maxBackwardMillis * time.Millisecond)
+	id2, err := idWorker.NextId()
+	if err != nil {
+		t.Fatal("小幅回拨不应该返回错误", err)
+	}
+	if id2 <= id {
+		t.Errorf("回拨后生成的id应该继续递增, id:%d, id2:%d", id, id2)
+	}
+
+	// 大幅回拨直接拒绝
+	clock.Add(-time.Second)
+	if _, err = idWorker.NextId(); err == nil {
+		t.Error("时钟大幅回拨时应该返回错误")
+	}
+	if _, err = idWorker.NextIds(10); err == nil {
+		t.Error("时钟大幅回拨时NextIds应该返回错误")
+	}
+
+	// 时钟恢复后继续发号
+	clock.Add(time.Second + time.Millisecond)
+	id3, err := idWorker.NextId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id3 <= id2 {
+		t.Errorf("时钟恢复后生成的id应该继续递增, id2:%d, id3:%d", id2, id3)
+	}
+}
+
+func TestIdWorker_SequenceOverflow(t *testing.T) {
+	clock := NewFakeClock(testTwepoch.Add(time.Second))
+	idWorker := newTestIdWorker(t, clock)
+
+	var last int64 = -1
+	for i := 0; i <= sequenceMask; i++ {
+		id, err := idWorker.NextId()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if timestamp, _, _, sequence := splitId(id); timestamp != 1000 || sequence != int64(i) {
+			t.Fatalf("sequence耗尽前应该在同一毫秒内, timestamp:%d, sequence:%d", timestamp, sequence)
+		}
+		last = id
+	}
+
+	id, err := idWorker.NextId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timestamp, _, _, sequence := splitId(id); timestamp != 1001 || sequence != 0 || id <= last {
+		t.Errorf("sequence耗尽后应该进入下一毫秒, timestamp:%d, sequence:%d", timestamp, sequence)
+	}
+}
+
+// 时钟不会前进，用于测试等待过程中ctx取消
+type stoppedClock struct {
+	*FakeClock
+}
+
+func (stoppedClock) After(d time.Duration) <-chan time.Time {
+	return nil
+}
+
+func TestIdWorker_SequenceOverflowCanceled(t *testing.T) {
+	clock := NewFakeClock(testTwepoch.Add(time.Second))
+	idWorker := newTestIdWorker(t, stoppedClock{clock})
+
+	for i := 0; i <= sequenceMask; i++ {
+		if _, err := idWorker.NextId(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelFunc()
+	if _, err := idWorker.NextIdWithContext(ctx); err != context.DeadlineExceeded {
+		t.Error("等待下一毫秒时ctx超时应该返回context.DeadlineExceeded", err)
+	}
+	if _, err := idWorker.NextIdsWithContext(ctx, 10); err != context.DeadlineExceeded {
+		t.Error("ctx超时后应该返回context.DeadlineExceeded", err)
+	}
+
+	clock.Add(time.Millisecond)
+	id, err := idWorker.NextId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timestamp, _, _, sequence := splitId(id); timestamp != 1001 || sequence != 0 {
+		t.Errorf("取消不应该影响后续发号, timestamp:%d, sequence:%d", timestamp, sequence)
+	}
+}
+
+func TestIdWorker_ClockRollbackCanceled(t *testing.T) {
+	clock := NewFakeClock(testTwepoch.Add(time.Second))
+	idWorker := newTestIdWorker(t, stoppedClock{clock})
+
+	if _, err := idWorker.NextId(); err != nil {
+		t.Fatal(err)
+	}
+	clock.Add(-time.Millisecond)
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelFunc()
+	if _, err := idWorker.NextIdWithContext(ctx); err != context.DeadlineExceeded {
+		t.Error("等待时钟追上时ctx超时应该返回context.DeadlineExceeded", err)
+	}
+}
+
+func TestIdWorker_NextIds(t *testing.T) {
+	clock := NewFakeClock(testTwepoch.Add(time.Second))
+	idWorker := newTestIdWorker(t, clock)
+
+	// 先消耗掉大部分sequence，让批量取号跨越毫秒
+	for i := 0; i < sequenceMask-9; i++ {
+		if _, err := idWorker.NextId(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ids, err := idWorker.NextIds(MaxNextIdsNum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != MaxNextIdsNum {
+		t.Fatalf("id数量不正确, len:%d", len(ids))
+	}
+	for i := 1; i < len(ids); i++ {
+		if ids[i] <= ids[i-1] {
+			t.Fatalf("批量生成的id应该严格递增, ids[%d]:%d, ids[%d]:%d", i-1, ids[i-1], i, ids[i])
+		}
+	}
+	if timestamp, _, _, sequence := splitId(ids[9]); timestamp != 1000 || sequence != sequenceMask {
+		t.Errorf("第10个id应该用完当前毫秒, timestamp:%d, sequence:%d", timestamp, sequence)
+	}
+	if timestamp, _, _, sequence := splitId(ids[10]); timestamp != 1001 || sequence != 0 {
+		t.Errorf("第11个id应该进入下一毫秒, timestamp:%d, sequence:%d", timestamp, sequence)
+	}
+
+	if _, err = idWorker.NextIds(MaxNextIdsNum + 1); err == nil {
+		t.Error("超过数量限制时应该返回错误")
+	}
+}
+
+func TestIdWorker_IssuedRange(t *testing.T) {
+	start := testTwepoch.Add(time.Second)
+	clock := NewFakeClock(start)
+	idWorker := newTestIdWorker(t, clock)
+	if _, _, issued := idWorker.IssuedRange(); issued {
+		t.Fatal("还没有发号时issued应该为false")
+	}
+
+	if _, err := idWorker.NextIds(3); err != nil {
+		t.Fatal(err)
+	}
+	clock.Add(5 * time.Millisecond)
+	if _, err := idWorker.NextId(); err != nil {
+		t.Fatal(err)
+	}
+	first, last, issued := idWorker.IssuedRange()
+	if !issued || !first.Equal(start) || !last.Equal(start.Add(5*time.Millisecond)) {
+		t.Fatalf("issued:%v first:%v last:%v", issued, first, last)
+	}
+}
+
+func TestNewIdWorker_Twepoch(t *testing.T) {
+	clock := NewFakeClock(testTwepoch)
+	if _, err := NewIdWorker(0, 0, testTwepoch.Add(time.Millisecond), WithClock(clock)); err == nil {
+		t.Fatal("twepoch晚于当前时间时应该返回错误")
+	}
+	if _, err := NewIdWorker(0, 0, testTwepoch, WithClock(clock), WithSafetyMargin(-time.Second)); err == nil {
+		t.Fatal("safetyMargin为负数时应该返回错误")
+	}
+}
+
+func TestIdWorker_SafetyMargin(t *testing.T) {
+	exhaust := DefaultLayout.ExhaustTime(testTwepoch)
+	margin := 24 * time.Hour
+
+	// 已进入预留期时拒绝启动
+	clock := NewFakeClock(exhaust.Add(-time.Hour))
+	if _, err := NewIdWorker(3, 7, testTwepoch, WithClock(clock), WithSafetyMargin(margin)); err == nil {
+		t.Fatal("进入安全预留期后应该拒绝启动")
+	}
+
+	// 运行中进入预留期时拒绝发号
+	clock = NewFakeClock(exhaust.Add(-margin - time.Millisecond))
+	idWorker, err := NewIdWorker(3, 7, testTwepoch, WithClock(clock), WithSafetyMargin(margin))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := idWorker.NextId(); err != nil {
+		t.Fatalf("预留期之前应该可以发号: %v", err)
+	}
+	info := idWorker.Info()
+	if !info.ExhaustTime.Equal(exhaust) || !info.ServeUntil.Equal(exhaust.Add(-margin)) {
+		t.Fatalf("exhaustTime:%v serveUntil:%v", info.ExhaustTime, info.ServeUntil)
+	}
+	clock.Set(info.ServeUntil)
+	if _, err := idWorker.NextId(); err == nil {
+		t.Fatal("进入安全预留期后应该拒绝发号")
+	}
+}
+
+func TestIdWorker_SequenceStart(t *testing.T) {
+	for _, start := range []SequenceStart{SequenceStartRandom, SequenceStartRotating} {
+		clock := NewFakeClock(testTwepoch.Add(time.Second))
+		idWorker, err := NewIdWorker(3, 7, testTwepoch, WithClock(clock), WithSequenceStart(start))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// 低流量时每个时间单位只发一个id，低位应该分布均匀
+		const shards = 16
+		counts := make([]int, shards)
+		for i := 0; i < 1600; i++ {
+			v, err := idWorker.NextId()
+			if err != nil {
+				t.Fatal(err)
+			}
+			counts[v%shards]++
+			clock.Add(time.Millisecond)
+		}
+		for shard, count := range counts {
+			if count < 50 || count > 150 {
+				t.Errorf("%s: 分片%d的id数量为%d，分布不均匀: %v", start, shard, count, counts)
+				break
+			}
+		}
+
+		// 同一时间单位内sequence回绕，用完全部sequence后才进入下一个时间单位
+		first, err := idWorker.NextId()
+		if err != nil {
+			t.Fatal(err)
+		}
+		firstTimestamp, _, _, firstSequence := splitId(first)
+		seen := map[int64]bool{firstSequence: true}
+		for i := 0; i < sequenceMask; i++ {
+			v, err := idWorker.NextId()
+			if err != nil {
+				t.Fatal(err)
+			}
+			timestamp, _, _, sequence := splitId(v)
+			if timestamp != firstTimestamp || seen[sequence] {
+				t.Fatalf("%s: 第%d个id timestamp:%d sequence:%d 不正确", start, i, timestamp, sequence)
+			}
+			seen[sequence] = true
+		}
+		v, err := idWorker.NextId()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if timestamp, _, _, _ := splitId(v); timestamp != firstTimestamp+1 {
+			t.Fatalf("%s: sequence用完后应该进入下一个时间单位", start)
+		}
+	}
+
+	if _, err := ParseSequenceStart("sideways"); err == nil {
+		t.Error("不支持的sequence起始方式应该返回错误")
+	}
+	if start, err := ParseSequenceStart("Random"); err != nil || start != SequenceStartRandom {
+		t.Error("解析sequence起始方式失败", start, err)
+	}
+}
+
+func TestIdWorker_Disable(t *testing.T) {
+	idWorker := newTestIdWorker(t, NewFakeClock(testTwepoch.Add(time.Second)))
+	conflict := errors.New("conflict")
+	lost := errors.New("lost")
+
+	// 冲突后注册失效，重新注册成功只移除注册失效的原因，仍然因为冲突停止发号
+	idWorker.Disable(conflict)
+	idWorker.Disable(lost)
+	idWorker.Enable(lost)
+	if _, err := idWorker.NextId(); err != conflict {
+		t.Fatalf("应该仍然因为冲突停止发号, err:%v", err)
+	}
+	// 注册失效后冲突
+	idWorker.Enable(conflict)
+	idWorker.Disable(lost)
+	idWorker.Disable(errors.New("conflict"))
+	idWorker.Enable(lost)
+	if _, err := idWorker.NextId(); err == nil || err.Error() != "conflict" {
+		t.Fatalf("应该仍然因为冲突停止发号, err:%v", err)
+	}
+	idWorker.Enable(conflict)
+	if _, err := idWorker.NextId(); err == nil {
+		t.Fatal("只能移除相同的原因")
+	}
+}
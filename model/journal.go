@@ -0,0 +1,139 @@
+package model
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/LazzyQ/msnowflake/basic"
+	"go.uber.org/zap"
+	"gopkg.in/natefinch/lumberjack.v2"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	journal *Journal
+)
+
+// 一次请求发出的id区间，同一个worker按(timestamp, sequence)顺序发号，
+// 区间[(TimestampFrom, SequenceFrom), (TimestampTo, SequenceTo)]内的id都由这次请求获得。
+// 同一时间单位内sequence从起始值开始递增，到最大值后回到0，直到起始值的前一个
+type JournalRecord struct {
+	Time          time.Time `json:"time"`
+	DataCenterId  int64     `json:"dc"`
+	WorkerId      int64     `json:"worker"`
+	TimestampFrom int64     `json:"ts_from"` // 距离twepoch的时间单位数，与id中的timestamp字段一致
+	SequenceFrom  int64     `json:"seq_from"`
+	TimestampTo   int64     `json:"ts_to"`
+	SequenceTo    int64     `json:"seq_to"`
+	OffsetFrom    int64     `json:"offset_from,omitempty"` // TimestampFrom的sequence起始值
+	OffsetTo      int64     `json:"offset_to,omitempty"`   // TimestampTo的sequence起始值
+	Count         int       `json:"count"`
+	Caller        string    `json:"caller,omitempty"`
+	RequestId     string    `json:"request_id,omitempty"`
+}
+
+// 按照位布局判断id是否在这条记录的区间内
+func (r JournalRecord) Contains(info IdInfo, layout Layout) bool {
+	if info.DataCenterId != r.DataCenterId || info.WorkerId != r.WorkerId {
+		return false
+	}
+	if info.Timestamp < r.TimestampFrom || info.Timestamp > r.TimestampTo {
+		return false
+	}
+	maxSequence := layout.MaxSequence()
+	if info.Timestamp == r.TimestampFrom && !sequenceBefore(r.SequenceFrom, info.Sequence, r.OffsetFrom, maxSequence) {
+		return false
+	}
+	if info.Timestamp == r.TimestampTo && !sequenceBefore(info.Sequence, r.SequenceTo, r.OffsetTo, maxSequence) {
+		return false
+	}
+	return true
+}
+
+// 按行追加JSON格式记录的审计日志
+type Journal struct {
+	w     io.Writer
+	mutex sync.Mutex
+}
+
+func NewJournal(w io.Writer) *Journal {
+	return &Journal{w: w}
+}
+
+// 未配置文件名时返回nil，不记录审计日志
+func InitJournal(config basic.JournalConfig) (*Journal, error) {
+	if len(config.Filename) == 0 {
+		return nil, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(config.Filename), 0755); err != nil {
+		zap.S().Errorw("创建审计日志目录失败", "filename", config.Filename, "err", err)
+		return nil, err
+	}
+	journal = NewJournal(&lumberjack.Logger{
+		Filename:   config.Filename,
+		MaxSize:    config.MaxSize,
+		MaxAge:     config.MaxAge,
+		MaxBackups: config.MaxBackups,
+	})
+	zap.S().Infow("开启审计日志", "filename", config.Filename)
+	return journal, nil
+}
+
+func GetJournal() *Journal {
+	return journal
+}
+
+func (j *Journal) Write(record JournalRecord) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	_, err = j.w.Write(b)
+	return err
+}
+
+// 从审计日志中查找包含id的记录，返回无法解析的行数(如进程退出时写了一半的记录)
+func SearchJournal(r io.Reader, layout Layout, infos []IdInfo, fn func(IdInfo, JournalRecord)) (skipped int, err error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var record JournalRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			skipped++
+			continue
+		}
+		for _, info := range infos {
+			if record.Contains(info, layout) {
+				fn(info, record)
+			}
+		}
+	}
+	return skipped, scanner.Err()
+}
+
+// 审计日志文件及lumberjack轮转出的备份文件，按从旧到新排序
+func JournalFiles(filename string) ([]string, error) {
+	ext := filepath.Ext(filename)
+	prefix := strings.TrimSuffix(filename, ext) + "-"
+	backups, err := filepath.Glob(prefix + "*" + ext)
+	if err != nil {
+		return nil, err
+	}
+	// 备份文件名中的时间为定长格式，按文件名排序即为时间顺序
+	sort.Strings(backups)
+	if _, err = os.Stat(filename); err == nil {
+		backups = append(backups, filename)
+	}
+	return backups, nil
+}
@@ -0,0 +1,133 @@
+package model
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdWorker_Journal(t *testing.T) {
+	var buf bytes.Buffer
+	clock := NewFakeClock(testTwepoch.Add(time.Second))
+	idWorker, err := NewIdWorker(3, 7, testTwepoch, WithClock(clock), WithJournal(NewJournal(&buf)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := NewRequestContext(context.Background(), RequestInfo{Caller: "a", RequestId: "r1"})
+	single, err := idWorker.NextIdWithContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 批量获取跨越sequence耗尽，id分布在两个时间单位
+	idWorker.sequence = sequenceMask - 2
+	ctx = NewRequestContext(context.Background(), RequestInfo{Caller: "b", RequestId: "r2"})
+	batch, err := idWorker.NextIdsWithContext(ctx, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = idWorker.NextId(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("应该写入3条记录: %q", buf.String())
+	}
+	var record JournalRecord
+	if err := json.Unmarshal([]byte(lines[1]), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Caller != "b" || record.RequestId != "r2" || record.Count != 5 ||
+		record.DataCenterId != 3 || record.WorkerId != 7 || record.TimestampTo != record.TimestampFrom+1 {
+		t.Fatalf("record: %+v", record)
+	}
+
+	find := func(v int64) []string {
+		var requestIds []string
+		skipped, err := SearchJournal(strings.NewReader(buf.String()+"{\"dc\":3,\n"), DefaultLayout, []IdInfo{Parse(v, testTwepoch, DefaultLayout)},
+			func(info IdInfo, record JournalRecord) {
+				requestIds = append(requestIds, record.RequestId)
+			})
+		if err != nil || skipped != 1 {
+			t.Fatalf("skipped:%d err:%v", skipped, err)
+		}
+		return requestIds
+	}
+	if got := find(single); len(got) != 1 || got[0] != "r1" {
+		t.Fatalf("id %d 应该由r1获得: %v", single, got)
+	}
+	for _, v := range batch {
+		if got := find(v); len(got) != 1 || got[0] != "r2" {
+			t.Fatalf("id %d 应该由r2获得: %v", v, got)
+		}
+	}
+	// 其他worker的id不匹配
+	other := DefaultLayout.compose(1000, 3, 8, 0)
+	if got := find(other); len(got) != 0 {
+		t.Fatalf("其他worker的id不应该匹配: %v", got)
+	}
+}
+
+func TestJournalFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "msnowflake-journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "journal.log")
+	for _, name := range []string{"journal.log", "journal-2020-03-02T00-00-00.000.log", "journal-2020-03-01T00-00-00.000.log", "other.log"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := JournalFiles(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join(dir, "journal-2020-03-01T00-00-00.000.log"),
+		filepath.Join(dir, "journal-2020-03-02T00-00-00.000.log"),
+		filename,
+	}
+	if strings.Join(files, ",") != strings.Join(want, ",") {
+		t.Fatalf("files: %v", files)
+	}
+}
+
+func TestJournalRecord_ContainsWrapped(t *testing.T) {
+	// 起始值为4000时同一时间单位内的发号顺序为4000..4095, 0..3999
+	record := JournalRecord{
+		TimestampFrom: 10, SequenceFrom: 4090, OffsetFrom: 4000,
+		TimestampTo: 11, SequenceTo: 5, OffsetTo: 100,
+	}
+	cases := []struct {
+		timestamp, sequence int64
+		want                bool
+	}{
+		{10, 4089, false},
+		{10, 4090, true},
+		{10, 4095, true},
+		{10, 0, true},
+		{10, 3999, true},
+		{11, 100, true},
+		{11, 4095, true},
+		{11, 5, true},
+		{11, 6, false},
+		{11, 99, false},
+		{9, 4095, false},
+		{12, 100, false},
+	}
+	for _, c := range cases {
+		info := IdInfo{Timestamp: c.timestamp, Sequence: c.sequence}
+		if got := record.Contains(info, DefaultLayout); got != c.want {
+			t.Errorf("timestamp:%d sequence:%d Contains = %v, want %v", c.timestamp, c.sequence, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"github.com/LazzyQ/msnowflake/basic"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// id的位布局，默认顺序为[timestamp][dataCenterId][workerId][sequence]
+type Layout struct {
+	Name           string
+	TimeUnit       time.Duration // timestamp的单位
+	TimestampBits  uint
+	DataCenterBits uint
+	WorkerBits     uint
+	SequenceBits   uint
+	// 为true时顺序为[timestamp][sequence][dataCenterId][workerId]
+	SequenceBeforeWorker bool
+	// 未配置twepoch时使用的起始时间
+	Twepoch string
+}
+
+var (
+	// Twitter的布局，也是本服务一直使用的布局
+	TwitterLayout = Layout{
+		Name:           "twitter",
+		TimeUnit:       time.Millisecond,
+		TimestampBits:  63 - timestampLeftShift,
+		DataCenterBits: dataCenterIdBits,
+		WorkerBits:     workerIdBits,
+		SequenceBits:   sequenceBits,
+		Twepoch:        basic.DefaultTwepoch,
+	}
+	// Sonyflake: 10ms为单位，[timestamp:39][sequence:8][machineId:16]
+	SonyflakeLayout = Layout{
+		Name:                 "sonyflake",
+		TimeUnit:             10 * time.Millisecond,
+		TimestampBits:        39,
+		WorkerBits:           16,
+		SequenceBits:         8,
+		SequenceBeforeWorker: true,
+		Twepoch:              "2014-09-01T00:00:00Z",
+	}
+	// Instagram: [timestamp:41][shardId:13][sequence:10]，shardId对应workerId
+	// timestamp最高位要到2046年才会用到，这里按40位处理，保证id为正数
+	InstagramLayout = Layout{
+		Name:          "instagram",
+		TimeUnit:      time.Millisecond,
+		TimestampBits: 40,
+		WorkerBits:    13,
+		SequenceBits:  10,
+		Twepoch:       "2011-08-24T21:07:01.721Z",
+	}
+	// Discord: [timestamp:42][internalWorkerId:5][processId:5][increment:12]
+	// timestamp最高位要到2084年才会用到，这里按41位处理，保证id为正数
+	DiscordLayout = Layout{
+		Name:           "discord",
+		TimeUnit:       time.Millisecond,
+		TimestampBits:  41,
+		DataCenterBits: 5,
+		WorkerBits:     5,
+		SequenceBits:   12,
+		Twepoch:        "2015-01-01T00:00:00Z",
+	}
+
+	DefaultLayout = TwitterLayout
+
+	layouts = []Layout{TwitterLayout, SonyflakeLayout, InstagramLayout, DiscordLayout}
+)
+
+// 解析位布局，可以是预置布局的名称，也可以是"timestamp,dataCenter,worker,sequence"格式的位数，如"41,5,5,12"
+func ParseLayout(s string) (Layout, error) {
+	for _, layout := range layouts {
+		if strings.EqualFold(s, layout.Name) {
+			return layout, nil
+		}
+	}
+
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return Layout{}, errors.New(fmt.Sprintf("layout格式不正确: %s", s))
+	}
+	bits := make([]uint, len(parts))
+	for i, part := range parts {
+		n, err := strconv.ParseUint(strings.TrimSpace(part), 10, 8)
+		if err != nil {
+			return Layout{}, errors.New(fmt.Sprintf("layout格式不正确: %s", s))
+		}
+		bits[i] = uint(n)
+	}
+	layout := Layout{
+		TimeUnit:       time.Millisecond,
+		TimestampBits:  bits[0],
+		DataCenterBits: bits[1],
+		WorkerBits:     bits[2],
+		SequenceBits:   bits[3],
+		Twepoch:        basic.DefaultTwepoch,
+	}
+	if err := layout.Validate(); err != nil {
+		return Layout{}, err
+	}
+	return layout, nil
+}
+
+// 所有字段加起来不能超过63位，保证id为正数
+func (l Layout) Validate() error {
+	if l.TimeUnit <= 0 {
+		return errors.New("layout的时间单位必须大于0")
+	}
+	if l.TimestampBits == 0 || l.SequenceBits == 0 {
+		return errors.New("layout的timestamp和sequence位数不能为0")
+	}
+	if l.TimestampBits+l.DataCenterBits+l.WorkerBits+l.SequenceBits > 63 {
+		return errors.New(fmt.Sprintf("layout总位数超过63: %s", l))
+	}
+	return nil
+}
+
+func (l Layout) String() string {
+	if len(l.Name) > 0 {
+		return l.Name
+	}
+	return fmt.Sprintf("%d,%d,%d,%d", l.TimestampBits, l.DataCenterBits, l.WorkerBits, l.SequenceBits)
+}
+
+// 各字段的左移位数
+func (l Layout) shifts() (timestampShift, dataCenterShift, workerShift, sequenceShift uint) {
+	if l.SequenceBeforeWorker {
+		workerShift = 0
+		dataCenterShift = workerShift + l.WorkerBits
+		sequenceShift = dataCenterShift + l.DataCenterBits
+		timestampShift = sequenceShift + l.SequenceBits
+		return
+	}
+	sequenceShift = 0
+	workerShift = sequenceShift + l.SequenceBits
+	dataCenterShift = workerShift + l.WorkerBits
+	timestampShift = dataCenterShift + l.DataCenterBits
+	return
+}
+
+func (l Layout) MaxTimestamp() int64 {
+	return mask(l.TimestampBits)
+}
+
+func (l Layout) MaxDataCenterId() int64 {
+	return mask(l.DataCenterBits)
+}
+
+func (l Layout) MaxWorkerId() int64 {
+	return mask(l.WorkerBits)
+}
+
+func (l Layout) MaxSequence() int64 {
+	return mask(l.SequenceBits)
+}
+
+// timestamp字段耗尽的时间，即从该时刻起id会溢出
+func (l Layout) ExhaustTime(twepoch time.Time) time.Time {
+	return l.time(l.units(twepoch) + l.MaxTimestamp() + 1).In(twepoch.Location())
+}
+
+// 把时间单位数换算为时间，timestamp位数较多时按秒换算避免纳秒溢出
+func (l Layout) time(units int64) time.Time {
+	unit := int64(l.TimeUnit)
+	if int64(time.Second)%unit == 0 {
+		perSecond := int64(time.Second) / unit
+		return time.Unix(units/perSecond, units%perSecond*unit)
+	}
+	return time.Unix(0, units*unit)
+}
+
+// 把twepoch换算为时间单位数
+func (l Layout) units(t time.Time) int64 {
+	return t.UnixNano() / int64(l.TimeUnit)
+}
+
+// 按照布局组装id，各字段不做范围检查
+func (l Layout) compose(timestamp, dataCenterId, workerId, sequence int64) int64 {
+	timestampShift, dataCenterShift, workerShift, sequenceShift := l.shifts()
+	return timestamp<<timestampShift | dataCenterId<<dataCenterShift | workerId<<workerShift | sequence<<sequenceShift
+}
+
+// id解析结果
+type IdInfo struct {
+	Id           int64     `json:"id"`
+	Time         time.Time `json:"time"`
+	Timestamp    int64     `json:"timestamp"` // 距离twepoch的时间单位数
+	DataCenterId int64     `json:"data_center_id"`
+	WorkerId     int64     `json:"worker_id"`
+	Sequence     int64     `json:"sequence"`
+}
+
+// 按照给定的twepoch和位布局拆解id
+func Parse(id int64, twepoch time.Time, layout Layout) IdInfo {
+	timestampShift, dataCenterShift, workerShift, sequenceShift := layout.shifts()
+
+	timestamp := (id >> timestampShift) & mask(layout.TimestampBits)
+	return IdInfo{
+		Id:           id,
+		Time:         layout.time(layout.units(twepoch) + timestamp).In(twepoch.Location()),
+		Timestamp:    timestamp,
+		DataCenterId: (id >> dataCenterShift) & mask(layout.DataCenterBits),
+		WorkerId:     (id >> workerShift) & mask(layout.WorkerBits),
+		Sequence:     (id >> sequenceShift) & mask(layout.SequenceBits),
+	}
+}
+
+func mask(bits uint) int64 {
+	return -1 ^ (-1 << bits)
+}
+
+// 不限定dataCenterId或workerId时使用
+const AnyId = -1
+
+// 返回按照给定的twepoch和位布局，在[start, end]时间范围内可能生成的最小和最大id，
+// 时间按布局的时间单位取整，两端都包含在内。dataCenterId、workerId为AnyId时不限定
+func IdRange(start, end, twepoch time.Time, layout Layout, dataCenterId, workerId int64) (min, max int64, err error) {
+	if end.Before(start) {
+		return 0, 0, errors.New("结束时间不能早于开始时间")
+	}
+	if dataCenterId != AnyId && (dataCenterId < 0 || dataCenterId > layout.MaxDataCenterId()) {
+		return 0, 0, errors.New("dataCenterId超过限制")
+	}
+	if workerId != AnyId && (workerId < 0 || workerId > layout.MaxWorkerId()) {
+		return 0, 0, errors.New("workerId超过限制")
+	}
+
+	epoch := layout.units(twepoch)
+	from, to := layout.units(start)-epoch, layout.units(end)-epoch
+	if to < 0 || from > layout.MaxTimestamp() {
+		return 0, 0, errors.New(fmt.Sprintf("时间范围不在可用区间内, 可用区间:[%s, %s)",
+			twepoch.Format(time.RFC3339Nano), layout.ExhaustTime(twepoch).Format(time.RFC3339Nano)))
+	}
+	// 超出可用区间的部分截断
+	if from < 0 {
+		from = 0
+	}
+	if to > layout.MaxTimestamp() {
+		to = layout.MaxTimestamp()
+	}
+
+	minDataCenterId, maxDataCenterId := int64(0), layout.MaxDataCenterId()
+	if dataCenterId != AnyId {
+		minDataCenterId, maxDataCenterId = dataCenterId, dataCenterId
+	}
+	minWorkerId, maxWorkerId := int64(0), layout.MaxWorkerId()
+	if workerId != AnyId {
+		minWorkerId, maxWorkerId = workerId, workerId
+	}
+	min = layout.compose(from, minDataCenterId, minWorkerId, 0)
+	max = layout.compose(to, maxDataCenterId, maxWorkerId, layout.MaxSequence())
+	return min, max, nil
+}
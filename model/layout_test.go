@@ -0,0 +1,221 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLayout(t *testing.T) {
+	layout, err := ParseLayout("41, 5,5,12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if layout.String() != "41,5,5,12" || layout.TimeUnit != time.Millisecond {
+		t.Errorf("解析结果不正确, layout:%s", layout)
+	}
+	if layout.MaxWorkerId() != maxWorkerId || layout.MaxSequence() != sequenceMask {
+		t.Errorf("解析结果与默认布局不一致, layout:%s", layout)
+	}
+
+	for _, preset := range layouts {
+		if layout, err = ParseLayout(preset.Name); err != nil || layout != preset {
+			t.Errorf("解析预置布局失败: %s", preset.Name)
+		}
+	}
+	if layout, err = ParseLayout("Sonyflake"); err != nil || layout != SonyflakeLayout {
+		t.Error("预置布局名称应该不区分大小写")
+	}
+
+	for _, s := range []string{"", "41,5,5", "41,5,5,x", "42,5,5,12", "0,5,5,12", "snowflake"} {
+		if _, err = ParseLayout(s); err == nil {
+			t.Errorf("非法的layout应该返回错误: %q", s)
+		}
+	}
+}
+
+func TestLayout_Presets(t *testing.T) {
+	for _, layout := range layouts {
+		if err := layout.Validate(); err != nil {
+			t.Errorf("预置布局%s不合法: %v", layout, err)
+		}
+		if _, err := time.Parse(time.RFC3339Nano, layout.Twepoch); err != nil {
+			t.Errorf("预置布局%s的twepoch不合法: %v", layout, err)
+		}
+	}
+}
+
+func parseTestTwepoch(t *testing.T, s string) time.Time {
+	twepoch, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return twepoch
+}
+
+func TestParse(t *testing.T) {
+	clock := NewFakeClock(testTwepoch.Add(98765 * time.Millisecond))
+	idWorker := newTestIdWorker(t, clock)
+
+	ids, err := idWorker.NextIds(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := Parse(ids[2], testTwepoch, DefaultLayout)
+	if !info.Time.Equal(clock.Now()) || info.Timestamp != 98765 {
+		t.Errorf("时间解析不正确, time:%v, timestamp:%d", info.Time, info.Timestamp)
+	}
+	if info.Id != ids[2] || info.DataCenterId != 3 || info.WorkerId != 7 || info.Sequence != 2 {
+		t.Errorf("字段解析不正确, info:%+v", info)
+	}
+}
+
+func TestParse_Discord(t *testing.T) {
+	// Discord文档中的示例id
+	twepoch := parseTestTwepoch(t, DiscordLayout.Twepoch)
+	info := Parse(175928847299117063, twepoch, DiscordLayout)
+	expected := time.Date(2016, 4, 30, 11, 18, 25, 796*int(time.Millisecond), time.UTC)
+	if !info.Time.Equal(expected) || info.DataCenterId != 1 || info.WorkerId != 0 || info.Sequence != 7 {
+		t.Errorf("Discord id解析不正确, info:%+v", info)
+	}
+}
+
+func TestIdWorker_Presets(t *testing.T) {
+	for _, layout := range layouts {
+		twepoch := parseTestTwepoch(t, layout.Twepoch)
+		now := twepoch.Add(1000 * time.Hour)
+		clock := NewFakeClock(now)
+		dataCenterId := layout.MaxDataCenterId()
+		workerId := layout.MaxWorkerId()
+		idWorker, err := NewIdWorker(dataCenterId, workerId, twepoch, WithClock(clock), WithLayout(layout))
+		if err != nil {
+			t.Fatal("创建IdWorker失败", layout, err)
+		}
+		if _, err = NewIdWorker(dataCenterId+1, workerId, twepoch, WithLayout(layout)); err == nil {
+			t.Errorf("%s: dataCenterId超过限制时应该返回错误", layout)
+		}
+
+		// 用完一个时间单位的sequence，再多取一个
+		count := int(layout.MaxSequence()) + 2
+		var last int64 = -1
+		for i := 0; i < count; i++ {
+			id, err := idWorker.NextId()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id <= last {
+				t.Fatalf("%s: id应该严格递增, last:%d, id:%d", layout, last, id)
+			}
+			last = id
+
+			info := Parse(id, twepoch, layout)
+			expectedTime, expectedSequence := now, int64(i)
+			if i == count-1 {
+				expectedTime, expectedSequence = now.Add(layout.TimeUnit), 0
+			}
+			if !info.Time.Equal(expectedTime) || info.Sequence != expectedSequence ||
+				info.DataCenterId != dataCenterId || info.WorkerId != workerId {
+				t.Fatalf("%s: 第%d个id解析结果不正确, info:%+v", layout, i, info)
+			}
+		}
+	}
+}
+
+func TestIdWorker_Sonyflake(t *testing.T) {
+	twepoch := parseTestTwepoch(t, SonyflakeLayout.Twepoch)
+	clock := NewFakeClock(twepoch.Add(12345 * time.Millisecond))
+	idWorker, err := NewIdWorker(0, 0x1234, twepoch, WithClock(clock), WithLayout(SonyflakeLayout))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = idWorker.NextId(); err != nil {
+		t.Fatal(err)
+	}
+	id, err := idWorker.NextId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// sonyflake: elapsed(10ms)<<24 | sequence<<16 | machineId
+	if expected := int64(1234)<<24 | 1<<16 | 0x1234; id != expected {
+		t.Errorf("Sonyflake id不正确, expected:%d, actual:%d", expected, id)
+	}
+
+	// 回拨不超过一个时间单位时等待
+	clock.Add(-10 * time.Millisecond)
+	if _, err = idWorker.NextId(); err != nil {
+		t.Error("回拨一个时间单位时应该等待而不是拒绝", err)
+	}
+}
+
+func TestLayout_ExhaustTime(t *testing.T) {
+	cases := []struct {
+		layout Layout
+		want   string
+	}{
+		{TwitterLayout, "2089-10-09T05:02:27.552Z"},
+		{SonyflakeLayout, "2188-11-16T03:28:58.88Z"},
+	}
+	for _, c := range cases {
+		twepoch := parseTestTwepoch(t, c.layout.Twepoch)
+		got := c.layout.ExhaustTime(twepoch).UTC().Format(time.RFC3339Nano)
+		if got != c.want {
+			t.Errorf("%s: ExhaustTime = %s, want %s", c.layout.Name, got, c.want)
+		}
+	}
+}
+
+func TestIdRange(t *testing.T) {
+	start := testTwepoch.Add(time.Hour)
+	end := start.Add(time.Minute)
+
+	min, max, err := IdRange(start, end, testTwepoch, DefaultLayout, AnyId, AnyId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info := Parse(min, testTwepoch, DefaultLayout); !info.Time.Equal(start) || info.DataCenterId != 0 || info.WorkerId != 0 || info.Sequence != 0 {
+		t.Fatalf("min: %+v", info)
+	}
+	if info := Parse(max, testTwepoch, DefaultLayout); !info.Time.Equal(end) || info.DataCenterId != maxDataCenterId ||
+		info.WorkerId != maxWorkerId || info.Sequence != sequenceMask {
+		t.Fatalf("max: %+v", info)
+	}
+
+	// 时间范围内生成的id都落在区间内
+	clock := NewFakeClock(start)
+	idWorker := newTestIdWorker(t, clock)
+	workerMin, workerMax, err := IdRange(start, end, testTwepoch, DefaultLayout, 3, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if workerMin < min || workerMax > max {
+		t.Fatalf("限定worker的区间[%d, %d]应该在[%d, %d]内", workerMin, workerMax, min, max)
+	}
+	for _, at := range []time.Time{start, start.Add(30 * time.Second), end} {
+		clock.Set(at)
+		v, err := idWorker.NextId()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v < workerMin || v > workerMax {
+			t.Fatalf("%s生成的id %d 不在区间[%d, %d]内", at, v, workerMin, workerMax)
+		}
+	}
+	if _, _, err := IdRange(start, start.Add(-time.Millisecond), testTwepoch, DefaultLayout, 3, 7); err == nil {
+		t.Fatal("结束时间早于开始时间时应该返回错误")
+	}
+
+	// 超出可用区间的部分截断
+	if min, _, err := IdRange(testTwepoch.Add(-time.Hour), end, testTwepoch, DefaultLayout, AnyId, AnyId); err != nil || min != 0 {
+		t.Fatalf("开始时间早于twepoch时最小id应该为0, min:%d err:%v", min, err)
+	}
+	exhaust := DefaultLayout.ExhaustTime(testTwepoch)
+	if _, max, err := IdRange(start, exhaust.Add(time.Hour), testTwepoch, DefaultLayout, AnyId, AnyId); err != nil || max != 1<<63-1 {
+		t.Fatalf("结束时间晚于耗尽时间时最大id应该为最大值, max:%d err:%v", max, err)
+	}
+	if _, _, err := IdRange(exhaust, exhaust.Add(time.Hour), testTwepoch, DefaultLayout, AnyId, AnyId); err == nil {
+		t.Fatal("时间范围不在可用区间内时应该返回错误")
+	}
+	if _, _, err := IdRange(start, end, testTwepoch, DefaultLayout, maxDataCenterId+1, AnyId); err == nil {
+		t.Fatal("dataCenterId超过限制时应该返回错误")
+	}
+}
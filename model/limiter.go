@@ -0,0 +1,221 @@
+package model
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"github.com/LazzyQ/msnowflake/basic"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	limiter *Limiter
+
+	ErrRateLimited   = errors.New("请求过于频繁")
+	ErrQuotaExceeded = errors.New("当日配额已用完")
+)
+
+// 没有单独配置限流的调用方使用的配置名
+const DefaultCaller = "*"
+
+// 最多保留状态的没有单独配置的调用方数量，超过时淘汰最久没有请求的调用方
+const maxDefaultCallers = 10000
+
+// 单个调用方的限流配置，各项为0时不限制
+type Limit struct {
+	Rate       float64 // 每秒允许获取的id数
+	Burst      int     // 令牌桶容量，限流时不能小于单次批量获取的最大数量
+	DailyQuota int64   // 每天(UTC)允许获取的id数
+}
+
+// 解析"caller=rate:burst:dailyQuota"格式的限流配置，caller为*时作为默认配置
+func ParseLimit(s string) (string, Limit, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || len(parts[0]) == 0 {
+		return "", Limit{}, errors.New(fmt.Sprintf("限流配置格式不正确: %s", s))
+	}
+	fields := strings.Split(parts[1], ":")
+	if len(fields) != 3 {
+		return "", Limit{}, errors.New(fmt.Sprintf("限流配置格式不正确: %s", s))
+	}
+	r, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil || r < 0 {
+		return "", Limit{}, errors.New(fmt.Sprintf("限流配置的rate不正确: %s", s))
+	}
+	burst, err := strconv.Atoi(fields[1])
+	// burst小于批量获取的数量时这样的请求永远无法通过
+	if err != nil || burst < 0 || (r > 0 && burst < MaxNextIdsNum) {
+		return "", Limit{}, errors.New(fmt.Sprintf("限流配置的burst不正确: %s", s))
+	}
+	quota, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil || quota < 0 {
+		return "", Limit{}, errors.New(fmt.Sprintf("限流配置的dailyQuota不正确: %s", s))
+	}
+	return parts[0], Limit{Rate: r, Burst: burst, DailyQuota: quota}, nil
+}
+
+// 调用方的统计
+type CallerStats struct {
+	Caller        string
+	Limit         Limit
+	Issued        int64 // 累计获取的id数
+	RateLimited   int64 // 因限流被拒绝的请求数
+	QuotaExceeded int64 // 因配额用完被拒绝的请求数
+	QuotaUsed     int64 // 当天已使用的配额
+}
+
+type callerState struct {
+	stats   CallerStats
+	bucket  *rate.Limiter // 不限流时为nil
+	quotaAt time.Time     // QuotaUsed所属的日期
+	element *list.Element // 在recent中的位置，单独配置的调用方为nil
+}
+
+// 按调用方限流并统计获取的id数，没有单独配置的调用方各自按默认配置限流。
+// 没有开启认证时调用方标识(IdRequest.Caller或Msnowflake-Caller)由客户端指定，
+// 可以冒充单独配置的调用方，也可以换名字获得新的默认配额，需要严格限流时应该开启认证
+type Limiter struct {
+	limits     map[string]Limit
+	callers    map[string]*callerState
+	recent     *list.List // 没有单独配置的调用方，最近请求的在前
+	maxCallers int
+	clock      Clock
+	mutex      sync.Mutex
+}
+
+func NewLimiter(limits map[string]Limit, clock Clock) *Limiter {
+	if clock == nil {
+		clock = systemClock{}
+	}
+	return &Limiter{
+		limits:     limits,
+		callers:    make(map[string]*callerState),
+		recent:     list.New(),
+		maxCallers: maxDefaultCallers,
+		clock:      clock,
+	}
+}
+
+func InitLimiter(config basic.LimitConfig) (*Limiter, error) {
+	limits := make(map[string]Limit, len(config.Limits))
+	for _, s := range config.Limits {
+		caller, limit, err := ParseLimit(s)
+		if err != nil {
+			zap.S().Errorw("限流配置不正确", "limit", s, "err", err)
+			return nil, err
+		}
+		limits[caller] = limit
+	}
+	limiter = NewLimiter(limits, nil)
+	zap.S().Infow("限流配置完成", "limits", limits)
+	return limiter, nil
+}
+
+func GetLimiter() (*Limiter, error) {
+	if limiter == nil {
+		return nil, errors.New("limiter未完成初始化")
+	}
+	return limiter, nil
+}
+
+// caller获取n个id，超过限流返回ErrRateLimited，配额不足返回ErrQuotaExceeded。
+// n超过批量获取的最大数量时不计入，由NextIds返回参数错误
+func (l *Limiter) Allow(caller string, n int) error {
+	if n > MaxNextIdsNum {
+		return nil
+	}
+	now := l.clock.Now()
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	state := l.state(caller)
+	limit := state.stats.Limit
+	if day := now.UTC().Truncate(24 * time.Hour); !day.Equal(state.quotaAt) {
+		state.quotaAt = day
+		state.stats.QuotaUsed = 0
+	}
+	if limit.DailyQuota > 0 && state.stats.QuotaUsed+int64(n) > limit.DailyQuota {
+		state.stats.QuotaExceeded++
+		return ErrQuotaExceeded
+	}
+	if state.bucket != nil && !state.bucket.AllowN(now, n) {
+		state.stats.RateLimited++
+		return ErrRateLimited
+	}
+	state.stats.Issued += int64(n)
+	state.stats.QuotaUsed += int64(n)
+	return nil
+}
+
+// Allow通过后获取id失败时退还计入的数量和配额，令牌不退还
+func (l *Limiter) Refund(caller string, n int) {
+	if n > MaxNextIdsNum {
+		return
+	}
+	day := l.clock.Now().UTC().Truncate(24 * time.Hour)
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	// 状态已被淘汰时不需要退还
+	state, ok := l.callers[caller]
+	if !ok {
+		return
+	}
+	state.stats.Issued -= int64(n)
+	if state.quotaAt.Equal(day) {
+		state.stats.QuotaUsed -= int64(n)
+	}
+}
+
+// 调用方第一次出现时按配置创建，没有单独配置时使用默认配置，调用方必须持有mutex。
+// 没有单独配置的调用方最多保留maxCallers个，避免callers无限增长，被淘汰的调用方再次请求时重新计算
+func (l *Limiter) state(caller string) *callerState {
+	if state, ok := l.callers[caller]; ok {
+		if state.element != nil {
+			l.recent.MoveToFront(state.element)
+		}
+		return state
+	}
+	limit, configured := l.limits[caller]
+	if !configured {
+		limit = l.limits[DefaultCaller]
+	}
+	state := &callerState{stats: CallerStats{Caller: caller, Limit: limit}}
+	if limit.Rate > 0 {
+		state.bucket = rate.NewLimiter(rate.Limit(limit.Rate), limit.Burst)
+	}
+	l.callers[caller] = state
+	if !configured {
+		state.element = l.recent.PushFront(state)
+		if l.recent.Len() > l.maxCallers {
+			oldest := l.recent.Remove(l.recent.Back()).(*callerState)
+			delete(l.callers, oldest.stats.Caller)
+		}
+	}
+	return state
+}
+
+// 按调用方名称排序返回统计
+func (l *Limiter) Stats() []CallerStats {
+	day := l.clock.Now().UTC().Truncate(24 * time.Hour)
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	stats := make([]CallerStats, 0, len(l.callers))
+	for _, state := range l.callers {
+		s := state.stats
+		if !state.quotaAt.Equal(day) {
+			s.QuotaUsed = 0
+		}
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Caller < stats[j].Caller
+	})
+	return stats
+}
@@ -0,0 +1,119 @@
+package model
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	caller, limit, err := ParseLimit("batch-job=100.5:200:1000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caller != "batch-job" || limit != (Limit{Rate: 100.5, Burst: 200, DailyQuota: 1000000}) {
+		t.Fatalf("caller:%s limit:%+v", caller, limit)
+	}
+	for _, s := range []string{"", "=1:1:1", "a=1:1", "a=x:1:1", "a=-1:1:1", "a=1:0:0", "a=1:99:0", "a=1:100:-1"} {
+		if _, _, err := ParseLimit(s); err == nil {
+			t.Errorf("%q 应该解析失败", s)
+		}
+	}
+}
+
+func TestLimiter_Rate(t *testing.T) {
+	clock := NewFakeClock(testTwepoch)
+	limiter := NewLimiter(map[string]Limit{"job": {Rate: 10, Burst: 20}}, clock)
+
+	if err := limiter.Allow("job", 20); err != nil {
+		t.Fatal(err)
+	}
+	if err := limiter.Allow("job", 1); err != ErrRateLimited {
+		t.Fatalf("令牌用完后应该被限流, err:%v", err)
+	}
+	clock.Add(500 * time.Millisecond)
+	if err := limiter.Allow("job", 5); err != nil {
+		t.Fatalf("补充令牌后应该可以获取, err:%v", err)
+	}
+	if err := limiter.Allow("job", 1); err != ErrRateLimited {
+		t.Fatalf("令牌用完后应该被限流, err:%v", err)
+	}
+	// 没有配置的调用方不限流
+	for i := 0; i < 10; i++ {
+		if err := limiter.Allow("other", 100); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 超过批量获取数量的请求不计入
+	if err := limiter.Allow("job", MaxNextIdsNum+1); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := limiter.Stats()
+	if len(stats) != 2 || stats[0].Caller != "job" || stats[1].Caller != "other" {
+		t.Fatalf("stats: %+v", stats)
+	}
+	if stats[0].Issued != 25 || stats[0].RateLimited != 2 || stats[1].Issued != 1000 {
+		t.Fatalf("stats: %+v", stats)
+	}
+}
+
+func TestLimiter_DailyQuota(t *testing.T) {
+	clock := NewFakeClock(time.Date(2020, 3, 1, 23, 59, 0, 0, time.UTC))
+	limiter := NewLimiter(map[string]Limit{DefaultCaller: {DailyQuota: 10}}, clock)
+
+	if err := limiter.Allow("a", 8); err != nil {
+		t.Fatal(err)
+	}
+	if err := limiter.Allow("a", 3); err != ErrQuotaExceeded {
+		t.Fatalf("配额不足时应该被拒绝, err:%v", err)
+	}
+	// 默认配置对每个调用方单独计算，一个调用方用完配额不影响其他调用方
+	if err := limiter.Allow("b", 10); err != nil {
+		t.Fatal(err)
+	}
+	// 获取失败时退还配额
+	if err := limiter.Allow("a", 2); err != nil {
+		t.Fatal(err)
+	}
+	limiter.Refund("a", 2)
+	if err := limiter.Allow("a", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Add(time.Minute)
+	if stats := limiter.Stats(); len(stats) != 2 || stats[0].QuotaUsed != 0 || stats[0].Issued != 10 || stats[0].QuotaExceeded != 1 {
+		t.Fatalf("stats: %+v", stats)
+	}
+	if err := limiter.Allow("a", 10); err != nil {
+		t.Fatalf("第二天配额应该重置, err:%v", err)
+	}
+}
+
+func TestLimiter_MaxCallers(t *testing.T) {
+	clock := NewFakeClock(testTwepoch)
+	limiter := NewLimiter(map[string]Limit{"job": {DailyQuota: 10}, DefaultCaller: {DailyQuota: 10}}, clock)
+	limiter.maxCallers = 3
+
+	if err := limiter.Allow("job", 10); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if err := limiter.Allow(fmt.Sprintf("caller-%d", i), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 只保留最近请求的没有单独配置的调用方，单独配置的调用方不会被淘汰
+	stats := limiter.Stats()
+	if len(stats) != 4 || stats[0].Caller != "caller-7" || stats[3].Caller != "job" {
+		t.Fatalf("stats: %+v", stats)
+	}
+	if err := limiter.Allow("job", 1); err != ErrQuotaExceeded {
+		t.Fatalf("单独配置的调用方的配额不应该被重置, err:%v", err)
+	}
+	// 被淘汰后退还不会使统计变为负数
+	limiter.Refund("caller-0", 1)
+	if len(limiter.Stats()) != 4 {
+		t.Fatal("退还不应该重新创建调用方")
+	}
+}
@@ -0,0 +1,266 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/LazzyQ/msnowflake/basic"
+	"go.uber.org/zap"
+	"os"
+	"sync"
+	"time"
+)
+
+const nodeKeyPrefix = "node/"
+
+var (
+	detector *Detector
+)
+
+// 节点在etcd中公布的信息
+type NodeInfo struct {
+	NodeId       string    `json:"node_id"`
+	Host         string    `json:"host"`
+	Pid          int       `json:"pid"`
+	DataCenterId int64     `json:"dc"`
+	WorkerId     int64     `json:"worker"`
+	StartTime    time.Time `json:"start_time"`
+	IssuedFrom   time.Time `json:"issued_from"` // 已发出的id的时间区间，还没有发号时为零值
+	IssuedTo     time.Time `json:"issued_to"`
+	UpdateTime   time.Time `json:"update_time"`
+}
+
+// 检测其他节点是否使用了相同的dataCenterId和workerId。
+// 每个节点在命名空间的node/下公布自己的信息并定期更新已发出的时间区间，同时watch其他节点，
+// 发现冲突时报警并停止发号。只能发现连接同一个etcd且使用相同命名空间的节点，
+// 这些节点的workerId已经由worker/下的注册保证不重复，检测的是绕过注册的情况，
+// 如没有注册直接创建IdWorker，或注册失效、workerId被其他节点接手后还没有停止发号。
+// 不同etcd集群或不同命名空间中的节点互相看不到，无法检测
+type Detector struct {
+	etcd      *basic.Etcd
+	idWorker  *IdWorker
+	info      NodeInfo
+	key       string
+	interval  time.Duration
+	tx        *basic.TxResponse
+	watch     *basic.WatchKeyChangeResponse
+	stop      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// 公布节点信息并开始检测冲突，已经存在冲突的节点时同样会停止发号
+func StartDetector(etcd *basic.Etcd, idWorker *IdWorker, interval time.Duration) (*Detector, error) {
+	host, nodeId := newNodeId()
+	info := idWorker.Info()
+	d := &Detector{
+		etcd:     etcd,
+		idWorker: idWorker,
+		info: NodeInfo{
+			NodeId:       nodeId,
+			Host:         host,
+			Pid:          os.Getpid(),
+			DataCenterId: info.DataCenterId,
+			WorkerId:     info.WorkerId,
+			StartTime:    time.Now(),
+		},
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+	d.key = nodeKeyPrefix + d.info.NodeId
+
+	if err := d.register(); err != nil {
+		return nil, err
+	}
+	if err := d.startWatch(); err != nil {
+		d.watch.CancelFunc()
+		_ = revoke(d.tx)
+		return nil, err
+	}
+
+	d.wg.Add(1)
+	go d.run()
+	return d, nil
+}
+
+// 主机名和进程内唯一的节点标识
+func newNodeId() (host, nodeId string) {
+	host, _ = os.Hostname()
+	return host, fmt.Sprintf("%s-%d-%d", host, os.Getpid(), time.Now().UnixNano())
+}
+
+func InitDetector(interval time.Duration) (*Detector, error) {
+	idWorker, err := GetIdWorker()
+	if err != nil {
+		return nil, err
+	}
+	if detector, err = StartDetector(basic.GetEtcd(), idWorker, interval); err != nil {
+		return nil, err
+	}
+	return detector, nil
+}
+
+// 注册本节点的信息并持续续约
+func (d *Detector) register() error {
+	value, err := d.value()
+	if err != nil {
+		return err
+	}
+	tx, err := d.etcd.TxKeepaliveWithTTL(d.key, value, workerTTL)
+	if err != nil {
+		return err
+	}
+	if !tx.Success {
+		zap.S().Errorw("节点信息注册到etcd失败", "key", d.key, "value", tx.Value)
+		return errors.New("节点信息注册失败")
+	}
+	d.tx = tx
+	return nil
+}
+
+// 先watch再读取已有节点，避免漏掉两者之间注册的节点
+func (d *Detector) startWatch() error {
+	d.watch = d.etcd.WatchWithPrefixKey(nodeKeyPrefix)
+	return d.checkAll()
+}
+
+func (d *Detector) run() {
+	defer d.wg.Done()
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	var (
+		done   = d.tx.Done
+		tick   = ticker.C
+		events = d.watch.Event
+		// 注册失效或watch结束后定时重试，done或events为nil表示需要重试
+		retry   <-chan time.Time
+		backoff = reregisterBackoff
+	)
+	// 不能watch其他节点时无法排除冲突，停止发号直到重新watch成功
+	unwatched := errors.New("无法检测其他节点是否使用相同的dataCenterId和workerId")
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-done:
+			// 其他节点看不到本节点，重新注册，期间继续检测其他节点
+			zap.S().Errorw("节点信息在etcd中的注册已失效，重新注册", "key", d.key)
+			_ = revoke(d.tx)
+			done, tick = nil, nil
+			retry = time.After(backoff)
+		case event, ok := <-events:
+			if !ok {
+				zap.S().Errorw("watch其他节点的信息失败，停止发号并重新watch", "err", d.watch.Err())
+				d.idWorker.Disable(unwatched)
+				events = nil
+				retry = time.After(backoff)
+				continue
+			}
+			switch event.Type {
+			case basic.KeyCompactedChangeEvent:
+				// 可能漏掉了其他节点的注册，重新读取全部节点
+				if err := d.checkAll(); err != nil {
+					zap.S().Warnw("重新读取其他节点的信息失败", "err", err)
+				}
+			case basic.KeyCreateChangeEvent, basic.KeyUpdateChangeEvent:
+				d.check(event.Value)
+			}
+		case <-retry:
+			if done == nil {
+				if err := d.register(); err != nil {
+					zap.S().Warnw("重新注册节点信息失败", "key", d.key, "err", err)
+				} else {
+					zap.S().Infow("重新注册节点信息成功", "key", d.key)
+					done, tick = d.tx.Done, ticker.C
+				}
+			}
+			if events == nil {
+				if err := d.startWatch(); err != nil {
+					zap.S().Warnw("重新watch其他节点的信息失败", "err", err)
+					d.watch.CancelFunc()
+				} else {
+					zap.S().Infow("重新watch其他节点的信息成功，恢复发号")
+					events = d.watch.Event
+					d.idWorker.Enable(unwatched)
+				}
+			}
+			if done != nil && events != nil {
+				retry, backoff = nil, reregisterBackoff
+				continue
+			}
+			if backoff *= 2; backoff > maxReregisterBackoff {
+				backoff = maxReregisterBackoff
+			}
+			retry = time.After(backoff)
+		case <-tick:
+			if err := d.publish(); err != nil {
+				zap.S().Warnw("公布节点信息失败", "key", d.key, "err", err)
+			}
+		}
+	}
+}
+
+// 更新已发出的时间区间，其他节点每次收到更新都会重新检测
+func (d *Detector) publish() error {
+	value, err := d.value()
+	if err != nil {
+		return err
+	}
+	return d.etcd.PutWithLease(d.key, value, d.tx.LeaseID)
+}
+
+func (d *Detector) value() (string, error) {
+	info := d.info
+	info.IssuedFrom, info.IssuedTo, _ = d.idWorker.IssuedRange()
+	info.UpdateTime = time.Now()
+	value, err := json.Marshal(info)
+	return string(value), err
+}
+
+func (d *Detector) checkAll() error {
+	_, values, err := d.etcd.GetWithPrefixKey(nodeKeyPrefix)
+	if err != nil {
+		return err
+	}
+	for _, v := range values {
+		d.check(v)
+	}
+	return nil
+}
+
+func (d *Detector) check(value []byte) {
+	var peer NodeInfo
+	if err := json.Unmarshal(value, &peer); err != nil {
+		zap.S().Warnw("节点信息格式不正确", "value", string(value), "err", err)
+		return
+	}
+	if peer.NodeId == d.info.NodeId || peer.DataCenterId != d.info.DataCenterId || peer.WorkerId != d.info.WorkerId {
+		return
+	}
+	zap.S().Errorw("检测到其他节点使用相同的dataCenterId和workerId，停止发号",
+		"dataCenterId", peer.DataCenterId,
+		"workerId", peer.WorkerId,
+		"node", d.info.NodeId,
+		"peer", peer.NodeId,
+		"peerHost", peer.Host,
+		"peerStartTime", peer.StartTime,
+		"peerIssuedFrom", peer.IssuedFrom,
+		"peerIssuedTo", peer.IssuedTo)
+	d.idWorker.Disable(errors.New(fmt.Sprintf("节点%s使用了相同的dataCenterId:%d和workerId:%d",
+		peer.NodeId, peer.DataCenterId, peer.WorkerId)))
+}
+
+// 本节点的信息，不包含已发出的时间区间
+func (d *Detector) Info() NodeInfo {
+	return d.info
+}
+
+// 停止检测并删除本节点的信息
+func (d *Detector) Close() {
+	d.closeOnce.Do(func() {
+		close(d.stop)
+		d.wg.Wait()
+		d.watch.CancelFunc()
+		_ = revoke(d.tx)
+	})
+}
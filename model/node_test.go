@@ -0,0 +1,144 @@
+package model
+
+import (
+	"context"
+	"github.com/LazzyQ/msnowflake/basic"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 需要etcd，通过MSNOWFLAKE_TEST_ETCD指定地址，未指定时跳过
+func testEtcd(t *testing.T) *basic.Etcd {
+	endpoints := os.Getenv("MSNOWFLAKE_TEST_ETCD")
+	if len(endpoints) == 0 {
+		t.Skip("未设置MSNOWFLAKE_TEST_ETCD")
+	}
+	etcd, err := basic.NewEtcd(basic.EtcdConfig{
+		Endpoints:      strings.Split(endpoints, ","),
+		ConnectTimeout: 5 * time.Second,
+		ReadTimeout:    2 * time.Second,
+	})
+	if err != nil {
+		t.Fatal("初始化etcd失败", err)
+	}
+	return etcd
+}
+
+func TestDetector_Reregister(t *testing.T) {
+	etcd := testEtcd(t)
+	defer etcd.Close()
+	idWorker, err := NewIdWorker(29, 29, testTwepoch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := StartDetector(etcd, idWorker, time.Second)
+	if err != nil {
+		t.Fatal("启动检测失败", err)
+	}
+	defer d.Close()
+
+	// 租约失效后重新注册节点信息
+	lost := d.tx.LeaseID
+	if _, err = d.tx.Lease.Revoke(context.Background(), lost); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(3 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		kvs, _, err := etcd.ListWithContext(context.Background(), d.key)
+		if err == nil && len(kvs) == 1 && kvs[0].Lease != int64(lost) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("租约失效后应该重新注册节点信息", err)
+		}
+	}
+}
+
+func TestDetector_Rewatch(t *testing.T) {
+	etcd := testEtcd(t)
+	defer etcd.Close()
+	workers := make([]*IdWorker, 2)
+	for i := range workers {
+		var err error
+		if workers[i], err = NewIdWorker(28, 28, testTwepoch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first, err := StartDetector(etcd, workers[0], time.Second)
+	if err != nil {
+		t.Fatal("启动检测失败", err)
+	}
+	defer first.Close()
+
+	// watch结束后重新watch，之后注册的冲突节点仍然可以被发现
+	first.watch.CancelFunc()
+	second, err := StartDetector(etcd, workers[1], time.Second)
+	if err != nil {
+		t.Fatal("启动检测失败", err)
+	}
+	defer second.Close()
+	for deadline := time.Now().Add(3 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if _, err = workers[0].NextId(); err != nil && strings.Contains(err.Error(), second.info.NodeId) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("重新watch后应该检测到冲突", err)
+		}
+	}
+}
+
+func TestRegistration_LostAfterConflict(t *testing.T) {
+	etcd := testEtcd(t)
+	defer etcd.Close()
+	workers := make([]*IdWorker, 2)
+	for i := range workers {
+		var err error
+		if workers[i], err = NewIdWorker(27, 27, testTwepoch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	registration, err := RegisterWorker(etcd, 27)
+	if err != nil {
+		t.Fatal("注册失败", err)
+	}
+	defer registration.Close()
+	registration.DisableOnLost(workers[0])
+	first, err := StartDetector(etcd, workers[0], time.Second)
+	if err != nil {
+		t.Fatal("启动检测失败", err)
+	}
+	defer first.Close()
+	// 模拟没有注册直接使用相同workerId的节点
+	second, err := StartDetector(etcd, workers[1], time.Second)
+	if err != nil {
+		t.Fatal("启动检测失败", err)
+	}
+	defer second.Close()
+	for deadline := time.Now().Add(3 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if _, err = workers[0].NextId(); err != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("应该检测到冲突")
+		}
+	}
+
+	// 冲突期间注册失效并重新注册，冲突的节点仍然存在，不能恢复发号
+	lease := registration.tx.LeaseID
+	if _, err = registration.tx.Lease.Revoke(context.Background(), lease); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(3 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		kvs, _, err := etcd.ListWithContext(context.Background(), registration.Key)
+		if err == nil && len(kvs) == 1 && kvs[0].Lease != int64(lease) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("租约失效后应该重新注册", err)
+		}
+	}
+	if _, err = workers[0].NextId(); err == nil || !strings.Contains(err.Error(), second.info.NodeId) {
+		t.Fatal("重新注册后应该仍然因为冲突停止发号", err)
+	}
+}
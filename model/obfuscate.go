@@ -0,0 +1,82 @@
+package model
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// 63位上的非平衡Feistel网络，高低两半分别为32位和31位，每轮交换一次
+const (
+	obfuscateBits   = 63
+	obfuscateRounds = 8 // 必须是偶数，保证最后高低两半的位数回到初始状态
+	minObfuscateKey = 16
+)
+
+// 带密钥的可逆置换，把递增的id映射为看起来随机的63位正整数，隐藏发号量
+type Obfuscator struct {
+	roundKeys [obfuscateRounds][sha256.Size]byte
+}
+
+func NewObfuscator(key []byte) (*Obfuscator, error) {
+	if len(key) < minObfuscateKey {
+		return nil, errors.New(fmt.Sprintf("混淆密钥长度至少%d字节", minObfuscateKey))
+	}
+	o := &Obfuscator{}
+	for i := range o.roundKeys {
+		mac := hmac.New(sha256.New, key)
+		_, _ = mac.Write([]byte{'m', 's', 'n', 'o', 'w', 'f', 'l', 'a', 'k', 'e', byte(i)})
+		copy(o.roundKeys[i][:], mac.Sum(nil))
+	}
+	return o, nil
+}
+
+// 混淆id，id必须是非负数
+func (o *Obfuscator) Obfuscate(id int64) (int64, error) {
+	if id < 0 {
+		return 0, errors.New(fmt.Sprintf("id不能为负数: %d", id))
+	}
+	x := uint64(id)
+	lowBits := uint(obfuscateBits / 2)
+	for i := 0; i < obfuscateRounds; i++ {
+		highBits := obfuscateBits - lowBits
+		high, low := x>>lowBits, x&bitMask(lowBits)
+		x = low<<highBits | (high^o.round(i, low))&bitMask(highBits)
+		lowBits = highBits
+	}
+	return int64(x), nil
+}
+
+// 还原被混淆的id
+func (o *Obfuscator) Reveal(id int64) (int64, error) {
+	if id < 0 {
+		return 0, errors.New(fmt.Sprintf("id不能为负数: %d", id))
+	}
+	x := uint64(id)
+	// 偶数轮之后低位的位数与初始状态相同
+	lowBits := uint(obfuscateBits / 2)
+	for i := obfuscateRounds - 1; i >= 0; i-- {
+		highBits := obfuscateBits - lowBits
+		// 这一轮加密前低位有highBits位，结果的高位就是加密前的低位
+		prevLow, mixed := x>>lowBits, x&bitMask(lowBits)
+		prevHigh := (mixed ^ o.round(i, prevLow)) & bitMask(lowBits)
+		x = prevHigh<<highBits | prevLow
+		lowBits = highBits
+	}
+	return int64(x), nil
+}
+
+// 轮函数: SHA256(轮密钥 || 输入)的前8个字节
+func (o *Obfuscator) round(i int, x uint64) uint64 {
+	var buf [sha256.Size + 8]byte
+	copy(buf[:], o.roundKeys[i][:])
+	binary.BigEndian.PutUint64(buf[sha256.Size:], x)
+	sum := sha256.Sum256(buf[:])
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+func bitMask(bits uint) uint64 {
+	return 1<<bits - 1
+}
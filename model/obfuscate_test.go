@@ -0,0 +1,105 @@
+package model
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func newTestObfuscator(t *testing.T, key string) *Obfuscator {
+	o, err := NewObfuscator([]byte(key))
+	if err != nil {
+		t.Fatal("创建Obfuscator失败", err)
+	}
+	return o
+}
+
+func TestNewObfuscator(t *testing.T) {
+	if _, err := NewObfuscator([]byte("short")); err == nil {
+		t.Error("密钥过短应该返回错误")
+	}
+}
+
+func TestObfuscator_Reveal(t *testing.T) {
+	o := newTestObfuscator(t, "0123456789abcdef")
+	rnd := rand.New(rand.NewSource(1))
+
+	ids := []int64{0, 1, 2, math.MaxInt64}
+	for i := 0; i < 10000; i++ {
+		ids = append(ids, rnd.Int63())
+	}
+	for _, id := range ids {
+		obfuscated, err := o.Obfuscate(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if obfuscated < 0 {
+			t.Fatalf("混淆后的id应该是非负数, id:%d, obfuscated:%d", id, obfuscated)
+		}
+		revealed, err := o.Reveal(obfuscated)
+		if err != nil || revealed != id {
+			t.Fatalf("还原结果不正确, id:%d, obfuscated:%d, revealed:%d, err:%v", id, obfuscated, revealed, err)
+		}
+	}
+
+	if _, err := o.Obfuscate(-1); err == nil {
+		t.Error("负数应该返回错误")
+	}
+}
+
+func TestObfuscator_HidesSequence(t *testing.T) {
+	o := newTestObfuscator(t, "0123456789abcdef")
+	other := newTestObfuscator(t, "fedcba9876543210")
+
+	var (
+		last       int64 = -1
+		increasing int
+		seen       = make(map[int64]struct{})
+	)
+	for id := int64(1 << 22); id < 1<<22+10000; id++ {
+		obfuscated, _ := o.Obfuscate(id)
+		if _, ok := seen[obfuscated]; ok {
+			t.Fatalf("混淆结果重复: %d", obfuscated)
+		}
+		seen[obfuscated] = struct{}{}
+		if obfuscated > last {
+			increasing++
+		}
+		last = obfuscated
+
+		if o2, _ := other.Obfuscate(id); o2 == obfuscated {
+			t.Fatalf("不同密钥的混淆结果不应该相同, id:%d", id)
+		}
+	}
+	// 随机排列中相邻两个数递增的概率约为1/2
+	if increasing < 4000 || increasing > 6000 {
+		t.Errorf("混淆结果仍然有明显的顺序, increasing:%d", increasing)
+	}
+}
+
+func TestIdWorker_Reveal(t *testing.T) {
+	clock := NewFakeClock(testTwepoch.Add(1000))
+	plain := newTestIdWorker(t, clock)
+	if _, err := plain.Reveal(1); err == nil {
+		t.Error("未开启混淆时Reveal应该返回错误")
+	}
+
+	o := newTestObfuscator(t, "0123456789abcdef")
+	idWorker, err := NewIdWorker(3, 7, testTwepoch, WithClock(clock), WithObfuscator(o))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids, err := idWorker.NextIds(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range ids {
+		id, err := idWorker.Reveal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, dataCenterId, workerId, sequence := splitId(id); dataCenterId != 3 || workerId != 7 || sequence != int64(i) {
+			t.Errorf("还原后的id字段不正确, id:%d", id)
+		}
+	}
+}
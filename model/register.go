@@ -0,0 +1,237 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/LazzyQ/msnowflake/basic"
+	"go.uber.org/zap"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	workerKeyPrefix = "worker/"
+	workerTTL       = 2 // worker注册的租约时间(s)
+	// 自动分配workerId时持有的锁，避免大量节点同时启动时互相抢占
+	workerLockKey     = "lock/worker"
+	workerLockTTL     = 5
+	workerLockTimeout = 30 * time.Second
+	// 注册失效后重新注册的等待时间
+	reregisterBackoff    = 100 * time.Millisecond
+	maxReregisterBackoff = 5 * time.Second
+)
+
+// workerId在etcd中的注册，第一次注册的租约失效后Done被关闭，此时其他节点可能已经占用该workerId。
+// DisableOnLost重新注册成功后恢复发号，Done不会重新打开
+type Registration struct {
+	WorkerId int64
+	Key      string
+	Done     <-chan struct{}
+	etcd     *basic.Etcd
+	assigner *Assigner // leader分配时参与选举，Close时退出
+	closed   chan struct{}
+	mutex    sync.Mutex
+	tx       *basic.TxResponse // 重新注册后替换为新的租约
+}
+
+func newRegistration(etcd *basic.Etcd, workerId int64, tx *basic.TxResponse) *Registration {
+	return &Registration{
+		WorkerId: workerId,
+		Key:      workerKey(workerId),
+		Done:     tx.Done,
+		etcd:     etcd,
+		closed:   make(chan struct{}),
+		tx:       tx,
+	}
+}
+
+func workerKey(workerId int64) string {
+	return workerKeyPrefix + strconv.FormatInt(workerId, 10)
+}
+
+// 在etcd中注册workerId，已被其他节点占用时返回错误
+func RegisterWorker(etcd *basic.Etcd, workerId int64) (*Registration, error) {
+	key := workerKey(workerId)
+	txResponse, err := etcd.TxKeepaliveWithTTL(key, strconv.FormatInt(workerId, 10), workerTTL)
+	if err != nil {
+		return nil, err
+	}
+	if !txResponse.Success {
+		zap.S().Errorw("worker注册到etcd失败", "key", key, "value", txResponse.Value)
+		return nil, errors.New("worker注册失败")
+	}
+	return newRegistration(etcd, workerId, txResponse), nil
+}
+
+// 从[0, maxWorkerId]中选择一个没有被占用的workerId注册，与指定workerId注册的节点共用同一个命名空间。
+// 选择时持有分布式锁，多个节点同时启动时依次分配
+func LeaseWorkerId(etcd *basic.Etcd, maxWorkerId int64) (registration *Registration, err error) {
+	ctx, cancelFunc := context.WithTimeout(context.Background(), workerLockTimeout)
+	defer cancelFunc()
+	err = etcd.WithLock(ctx, workerLockKey, workerLockTTL, func(ctx context.Context) (err error) {
+		registration, err = leaseWorkerId(etcd, maxWorkerId)
+		return
+	})
+	return
+}
+
+func leaseWorkerId(etcd *basic.Etcd, maxWorkerId int64) (*Registration, error) {
+	_, values, err := etcd.GetWithPrefixKey(workerKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	used := make(map[string]bool, len(values))
+	for _, v := range values {
+		used[string(v)] = true
+	}
+	for workerId := int64(0); workerId <= maxWorkerId; workerId++ {
+		if used[strconv.FormatInt(workerId, 10)] {
+			continue
+		}
+		// 指定workerId注册的节点不经过锁，仍然可能同时抢占，失败后继续尝试下一个
+		txResponse, err := etcd.TxKeepaliveWithTTL(workerKey(workerId), strconv.FormatInt(workerId, 10), workerTTL)
+		if err != nil {
+			return nil, err
+		}
+		if txResponse.Success {
+			zap.S().Infow("自动分配workerId", "workerId", workerId)
+			return newRegistration(etcd, workerId, txResponse), nil
+		}
+	}
+	return nil, errors.New("没有空闲的workerId")
+}
+
+// 撤销租约，释放workerId
+func (r *Registration) Close() error {
+	r.mutex.Lock()
+	select {
+	case <-r.closed:
+		r.mutex.Unlock()
+		return nil
+	default:
+	}
+	close(r.closed)
+	tx := r.tx
+	r.mutex.Unlock()
+
+	err := revoke(tx)
+	if r.assigner != nil {
+		r.assigner.Close()
+	}
+	return err
+}
+
+// 按配置的方式自动分配workerId
+func AssignWorkerId(etcd *basic.Etcd, config basic.SnowflakeConfig, maxWorkerId int64) (*Registration, error) {
+	switch config.GetWorkerAssignment() {
+	case basic.WorkerAssignmentLease:
+		return LeaseWorkerId(etcd, maxWorkerId)
+	case basic.WorkerAssignmentLeader:
+	default:
+		zap.S().Errorw("Snowflake的WorkerAssignment配置不正确", "workerAssignment", config.GetWorkerAssignment())
+		return nil, errors.New(fmt.Sprintf("不支持的workerId分配方式: %s", config.GetWorkerAssignment()))
+	}
+
+	reservations, err := ParseReservations(config.GetWorkerReservations())
+	if err != nil {
+		return nil, err
+	}
+	host, nodeId := newNodeId()
+	// 每个自动分配的节点都参与选举，leader退出后由其他节点接替
+	assigner := StartAssigner(etcd, nodeId, reservations, config.GetWorkerCooldown())
+	registration, err := RequestWorkerId(etcd, AssignRequest{
+		NodeId:       nodeId,
+		Host:         host,
+		DataCenterId: config.GetDataCenter(),
+		MaxWorkerId:  maxWorkerId,
+		RequestTime:  time.Now(),
+	}, config.GetWorkerAssignTimeout())
+	if err != nil {
+		assigner.Close()
+		return nil, err
+	}
+	registration.assigner = assigner
+	return registration, nil
+}
+
+// 撤销租约并停止续约，绑定在租约上的key随之删除
+func revoke(tx *basic.TxResponse) error {
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second)
+	defer cancelFunc()
+	_, err := tx.Lease.Revoke(ctx, tx.LeaseID)
+	if closeErr := tx.Lease.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// 注册失效后停止发号，避免与接手该workerId的节点重复，之后不断尝试重新注册同一个workerId，成功后恢复发号
+func (r *Registration) DisableOnLost(idWorker *IdWorker) {
+	lost := errors.New("worker注册已失效")
+	go func() {
+		for {
+			r.mutex.Lock()
+			tx := r.tx
+			r.mutex.Unlock()
+			select {
+			case <-tx.Done:
+			case <-r.closed:
+				return
+			}
+			// Close撤销租约时Done同样会被关闭，此时不是异常
+			select {
+			case <-r.closed:
+				return
+			default:
+			}
+			zap.S().Errorw("worker在etcd中的注册已失效，停止发号", "key", r.Key)
+			idWorker.Disable(lost)
+			// 续约停止时租约可能还没有过期，撤销后才能重新注册
+			_ = revoke(tx)
+			if !r.reregister() {
+				return
+			}
+			idWorker.Enable(lost)
+			zap.S().Infow("worker重新注册成功，恢复发号", "key", r.Key)
+		}
+	}()
+}
+
+// 重新注册同一个workerId直到成功，注册被关闭时返回false
+func (r *Registration) reregister() bool {
+	backoff := reregisterBackoff
+	for {
+		select {
+		case <-r.closed:
+			return false
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxReregisterBackoff {
+			backoff = maxReregisterBackoff
+		}
+		tx, err := r.etcd.TxKeepaliveWithTTL(r.Key, strconv.FormatInt(r.WorkerId, 10), workerTTL)
+		if err != nil {
+			zap.S().Warnw("worker重新注册失败", "key", r.Key, "err", err)
+			continue
+		}
+		if !tx.Success {
+			// 其他节点已经占用该workerId，等它释放后再注册
+			zap.S().Warnw("worker已被其他节点占用，等待重新注册", "key", r.Key, "value", tx.Value)
+			continue
+		}
+
+		r.mutex.Lock()
+		select {
+		case <-r.closed:
+			r.mutex.Unlock()
+			_ = revoke(tx)
+			return false
+		default:
+		}
+		r.tx = tx
+		r.mutex.Unlock()
+		return true
+	}
+}
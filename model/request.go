@@ -0,0 +1,32 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+// 请求的调用方和请求id，写入审计日志
+type RequestInfo struct {
+	Caller    string
+	RequestId string
+}
+
+type requestInfoKey struct{}
+
+func NewRequestContext(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+func RequestInfoFromContext(ctx context.Context) (RequestInfo, bool) {
+	info, ok := ctx.Value(requestInfoKey{}).(RequestInfo)
+	return info, ok
+}
+
+// 生成请求id，使用ULID，按时间有序
+func NewRequestId() (string, error) {
+	u, err := newUlid(time.Now().UnixNano() / int64(time.Millisecond))
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// 每个时间单位的sequence起始值
+type SequenceStart int
+
+const (
+	// 从0开始，低流量时id的低位几乎都是0
+	SequenceStartZero SequenceStart = iota
+	// 从随机值开始
+	SequenceStartRandom
+	// 每个时间单位的起始值比上一个加1
+	SequenceStartRotating
+)
+
+func (s SequenceStart) String() string {
+	switch s {
+	case SequenceStartZero:
+		return "zero"
+	case SequenceStartRandom:
+		return "random"
+	case SequenceStartRotating:
+		return "rotating"
+	default:
+		return fmt.Sprintf("SequenceStart(%d)", int(s))
+	}
+}
+
+func ParseSequenceStart(name string) (SequenceStart, error) {
+	if len(name) == 0 {
+		return SequenceStartZero, nil
+	}
+	for s := SequenceStartZero; s <= SequenceStartRotating; s++ {
+		if strings.EqualFold(name, s.String()) {
+			return s, nil
+		}
+	}
+	return SequenceStartZero, errors.New(fmt.Sprintf("不支持的sequence起始方式: %s", name))
+}
+
+// 下一个时间单位的sequence起始值，调用方必须持有mutex
+func (id *IdWorker) nextSequenceOffset() int64 {
+	switch id.sequenceStart {
+	case SequenceStartRandom:
+		return id.rand.Int63n(id.layout.MaxSequence() + 1)
+	case SequenceStartRotating:
+		return (id.sequenceOffset + 1) & id.layout.MaxSequence()
+	default:
+		return 0
+	}
+}
+
+// 从起始值offset开始按发号顺序，sequence a是否不晚于b
+func sequenceBefore(a, b, offset, maxSequence int64) bool {
+	return (a-offset)&maxSequence <= (b-offset)&maxSequence
+}
+
+func newRand() *rand.Rand {
+	return rand.New(rand.NewSource(time.Now().UnixNano()))
+}
@@ -0,0 +1,195 @@
+package model
+
+import (
+	"flag"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 长时间压测: go test ./model -run TestSimulation -msnowflake.soak=30m -timeout 0
+var (
+	soakDuration = flag.Duration("msnowflake.soak", 0, "模拟测试持续运行的时间，为0时只跑一轮")
+	simSeed      = flag.Int64("msnowflake.seed", 0, "模拟测试的随机种子，为0时使用当前时间")
+)
+
+const (
+	simWorkers       = 64
+	simIdsPerWorker  = 32 * 1024
+	simMaxJitter     = 3 * time.Millisecond  // 单个worker时钟的最大抖动
+	simRollback      = 50 * time.Millisecond // 注入的大幅回拨
+	simAdvanceChance = 0.002                 // 每次发号后共享时钟前进1ms的概率
+	simJitterChance  = 0.001                 // 每次发号后worker时钟抖动的概率
+	simRollbackRate  = 0.00005               // 每次发号后worker时钟大幅回拨的概率
+)
+
+// 在共享时钟上叠加偏移，模拟各节点时钟的抖动和回拨
+type jitterClock struct {
+	base   *FakeClock
+	offset int64
+}
+
+func (c *jitterClock) Now() time.Time {
+	return c.base.Now().Add(time.Duration(atomic.LoadInt64(&c.offset)))
+}
+
+func (c *jitterClock) After(d time.Duration) <-chan time.Time {
+	return c.base.After(d)
+}
+
+func (c *jitterClock) setOffset(d time.Duration) {
+	atomic.StoreInt64(&c.offset, int64(d))
+}
+
+type simResult struct {
+	dataCenterId  int64
+	workerId      int64
+	sequenceStart SequenceStart
+	ids           []int64
+	rollbacks     int
+}
+
+func TestSimulation_Uniqueness(t *testing.T) {
+	seed := *simSeed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	t.Log("seed: ", seed)
+	rnd := rand.New(rand.NewSource(seed))
+
+	idsPerWorker := simIdsPerWorker
+	if testing.Short() {
+		idsPerWorker /= 16
+	}
+
+	deadline := time.Now().Add(*soakDuration)
+	start := testTwepoch.Add(time.Hour)
+	for round := 1; ; round++ {
+		// 每轮使用新的worker，起始时间晚于上一轮，轮与轮之间互不影响
+		results := runSimulation(t, rnd.Int63(), start, idsPerWorker)
+		total, rollbacks := checkSimulation(t, results)
+		t.Logf("round %d: workers:%d, ids:%d, rollbacks:%d", round, len(results), total, rollbacks)
+		if t.Failed() || time.Now().After(deadline) {
+			return
+		}
+		start = start.Add(24 * time.Hour)
+	}
+}
+
+func runSimulation(t *testing.T, seed int64, start time.Time, idsPerWorker int) []*simResult {
+	base := NewFakeClock(start)
+	rnd := rand.New(rand.NewSource(seed))
+
+	// 随机挑选不重复的dataCenterId/workerId组合
+	pairs := rnd.Perm((maxDataCenterId + 1) * (maxWorkerId + 1))[:simWorkers]
+	results := make([]*simResult, simWorkers)
+	var wg sync.WaitGroup
+	for i, pair := range pairs {
+		clock := &jitterClock{base: base}
+		// 轮流使用各种sequence起始方式
+		sequenceStart := SequenceStart(i % int(SequenceStartRotating+1))
+		idWorker, err := NewIdWorker(int64(pair/(maxWorkerId+1)), int64(pair%(maxWorkerId+1)), testTwepoch,
+			WithClock(clock), WithSequenceStart(sequenceStart))
+		if err != nil {
+			t.Fatal("创建IdWorker失败", err)
+		}
+		result := &simResult{
+			dataCenterId:  idWorker.dataCenterId,
+			workerId:      idWorker.workerId,
+			sequenceStart: sequenceStart,
+			ids:           make([]int64, 0, idsPerWorker),
+		}
+		results[i] = result
+
+		wg.Add(1)
+		go func(idWorker *IdWorker, clock *jitterClock, rnd *rand.Rand) {
+			defer wg.Done()
+			simulateWorker(t, idWorker, clock, base, rnd, result, idsPerWorker)
+		}(idWorker, clock, rand.New(rand.NewSource(rnd.Int63())))
+	}
+	wg.Wait()
+	return results
+}
+
+func simulateWorker(t *testing.T, idWorker *IdWorker, clock *jitterClock, base *FakeClock, rnd *rand.Rand, result *simResult, idsPerWorker int) {
+	for len(result.ids) < idsPerWorker {
+		var (
+			ids []int64
+			err error
+		)
+		if rnd.Intn(4) == 0 {
+			ids, err = idWorker.NextIds(uint32(1 + rnd.Intn(MaxNextIdsNum)))
+		} else {
+			var id int64
+			if id, err = idWorker.NextId(); err == nil {
+				ids = []int64{id}
+			}
+		}
+		if err != nil {
+			if !strings.HasPrefix(err.Error(), "时钟回调") {
+				t.Error("发号失败", err)
+				return
+			}
+			// 被拒绝后恢复时钟
+			result.rollbacks++
+			clock.setOffset(0)
+		}
+		result.ids = append(result.ids, ids...)
+
+		p := rnd.Float64()
+		switch {
+		case p < simRollbackRate:
+			clock.setOffset(-simRollback)
+		case p < simRollbackRate+simJitterChance:
+			clock.setOffset(time.Duration(rnd.Int63n(int64(2*simMaxJitter))) - simMaxJitter)
+		case p < simRollbackRate+simJitterChance+simAdvanceChance:
+			base.Add(time.Millisecond)
+		}
+	}
+}
+
+// sequence从0开始时id单调递增，否则同一时间单位内sequence会回绕，只保证timestamp不减
+func simOrdered(sequenceStart SequenceStart, prev, id int64) bool {
+	if sequenceStart == SequenceStartZero {
+		return id > prev
+	}
+	prevTimestamp, _, _, _ := splitId(prev)
+	timestamp, _, _, _ := splitId(id)
+	return timestamp >= prevTimestamp && id != prev
+}
+
+func checkSimulation(t *testing.T, results []*simResult) (total, rollbacks int) {
+	for _, result := range results {
+		total += len(result.ids)
+		rollbacks += result.rollbacks
+	}
+
+	all := make([]int64, 0, total)
+	for _, result := range results {
+		for i, id := range result.ids {
+			if i > 0 && !simOrdered(result.sequenceStart, result.ids[i-1], id) {
+				t.Errorf("worker(%d,%d)生成的id顺序不正确, sequenceStart:%s, ids[%d]:%d, ids[%d]:%d",
+					result.dataCenterId, result.workerId, result.sequenceStart, i-1, result.ids[i-1], i, id)
+				return
+			}
+			if _, dataCenterId, workerId, _ := splitId(id); dataCenterId != result.dataCenterId || workerId != result.workerId {
+				t.Errorf("worker(%d,%d)生成的id字段不正确, id:%d", result.dataCenterId, result.workerId, id)
+				return
+			}
+		}
+		all = append(all, result.ids...)
+	}
+
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+	for i := 1; i < len(all); i++ {
+		if all[i] == all[i-1] {
+			t.Errorf("id重复: %d", all[i])
+			return
+		}
+	}
+	return
+}
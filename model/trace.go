@@ -0,0 +1,55 @@
+package model
+
+import (
+	"context"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+var tracer = otel.Tracer("github.com/LazzyQ/msnowflake/model")
+
+const (
+	batchSizeKey         = attribute.Key("msnowflake.batch_size")
+	dataCenterIdKey      = attribute.Key("msnowflake.data_center_id")
+	workerIdKey          = attribute.Key("msnowflake.worker_id")
+	rollbackWaitKey      = attribute.Key("msnowflake.clock_rollback_wait_ms") // 最近一次因时钟回拨等待的时间
+	rollbackRejectKey    = attribute.Key("msnowflake.clock_rollback_rejected")
+	sequenceExhaustedKey = attribute.Key("msnowflake.sequence_exhausted")
+)
+
+// 为一次发号请求创建span
+func (id *IdWorker) startSpan(ctx context.Context, name string, num uint32) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "IdWorker."+name, trace.WithAttributes(
+		batchSizeKey.Int64(int64(num)),
+		dataCenterIdKey.Int64(id.dataCenterId),
+		workerIdKey.Int64(id.workerId),
+	))
+}
+
+// 时钟回拨时记录等待或拒绝的时间
+func traceClockRollback(ctx context.Context, offset time.Duration, rejected bool) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	ms := int64(offset / time.Millisecond)
+	if rejected {
+		span.SetAttributes(rollbackRejectKey.Bool(true))
+		span.AddEvent("clock_rollback_rejected", trace.WithAttributes(rollbackWaitKey.Int64(ms)))
+		return
+	}
+	span.SetAttributes(rollbackWaitKey.Int64(ms))
+	span.AddEvent("clock_rollback_wait", trace.WithAttributes(rollbackWaitKey.Int64(ms)))
+}
+
+// 当前时间单位的sequence已经用完，需要等待下一个时间单位
+func traceSequenceExhausted(ctx context.Context) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	span.SetAttributes(sequenceExhaustedKey.Bool(true))
+	span.AddEvent("sequence_exhausted")
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+	"time"
+)
+
+func hasAttribute(span sdktrace.ReadOnlySpan, kv attribute.KeyValue) bool {
+	for _, attr := range span.Attributes() {
+		if attr == kv {
+			return true
+		}
+	}
+	return false
+}
+
+func TestIdWorker_Trace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	old := tracer
+	tracer = provider.Tracer("test")
+	defer func() { tracer = old }()
+
+	clock := NewFakeClock(testTwepoch.Add(time.Second))
+	idWorker := newTestIdWorker(t, clock)
+	lastSpan := func() sdktrace.ReadOnlySpan {
+		spans := recorder.Ended()
+		return spans[len(spans)-1]
+	}
+
+	if _, err := idWorker.NextIds(10); err != nil {
+		t.Fatal(err)
+	}
+	span := lastSpan()
+	if span.Name() != "IdWorker.NextIds" || !hasAttribute(span, batchSizeKey.Int64(10)) ||
+		!hasAttribute(span, dataCenterIdKey.Int64(3)) || !hasAttribute(span, workerIdKey.Int64(7)) {
+		t.Fatalf("span不正确: %s %v", span.Name(), span.Attributes())
+	}
+
+	// sequence耗尽
+	for i := 10; i <= sequenceMask; i++ {
+		if _, err := idWorker.NextId(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if hasAttribute(lastSpan(), sequenceExhaustedKey.Bool(true)) {
+		t.Fatal("sequence没有耗尽时不应该记录")
+	}
+	if _, err := idWorker.NextId(); err != nil {
+		t.Fatal(err)
+	}
+	if span = lastSpan(); !hasAttribute(span, sequenceExhaustedKey.Bool(true)) || span.Events()[0].Name != "sequence_exhausted" {
+		t.Fatalf("sequence耗尽时应该记录: %v", span.Attributes())
+	}
+
+	// 小幅回拨等待，大幅回拨拒绝
+	clock.Add(-2 * time.Millisecond)
+	if _, err := idWorker.NextId(); err != nil {
+		t.Fatal(err)
+	}
+	if span = lastSpan(); !hasAttribute(span, rollbackWaitKey.Int64(2)) {
+		t.Fatalf("回拨等待时应该记录: %v", span.Attributes())
+	}
+	clock.Add(-time.Second)
+	if _, err := idWorker.NextId(); err == nil {
+		t.Fatal("时钟大幅回拨时应该返回错误")
+	}
+	if span = lastSpan(); !hasAttribute(span, rollbackRejectKey.Bool(true)) || span.Status().Code != codes.Error {
+		t.Fatalf("回拨拒绝时应该记录: %v %v", span.Attributes(), span.Status())
+	}
+}
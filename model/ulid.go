@@ -0,0 +1,214 @@
+package model
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/LazzyQ/msnowflake/basic"
+	"go.uber.org/zap"
+	"time"
+)
+
+// ULID: [毫秒时间戳:48][随机数:80]，同一毫秒内随机数部分递增
+type Ulid [16]byte
+
+// UUIDv7: [毫秒时间戳:48][版本:4][计数器:12][变体:2][随机数:62]，同一毫秒内计数器递增
+type Uuid [16]byte
+
+const (
+	uuidCounterMask = 0xfff
+	// 每毫秒的计数器从[0, 0x7ff]中随机取值开始，留出至少2048个递增空间
+	uuidCounterSeedMask = 0x7ff
+)
+
+func (id *IdWorker) NextUlidsWithContext(ctx context.Context, num uint32) (_ []Ulid, err error) {
+	ctx, span := id.startSpan(ctx, "NextUlids", num)
+	defer func() { basic.EndSpan(span, err) }()
+	if err := checkBatchNum(ctx, num); err != nil {
+		return nil, err
+	}
+	ulids := make([]Ulid, num)
+	id.mutex.Lock()
+	defer id.mutex.Unlock()
+	for i := range ulids {
+		u, err := id.nextUlid(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ulids[i] = u
+	}
+	return ulids, nil
+}
+
+func (id *IdWorker) NextUuidsWithContext(ctx context.Context, num uint32) (_ []Uuid, err error) {
+	ctx, span := id.startSpan(ctx, "NextUuids", num)
+	defer func() { basic.EndSpan(span, err) }()
+	if err := checkBatchNum(ctx, num); err != nil {
+		return nil, err
+	}
+	uuids := make([]Uuid, num)
+	id.mutex.Lock()
+	defer id.mutex.Unlock()
+	for i := range uuids {
+		u, err := id.nextUuid(ctx)
+		if err != nil {
+			return nil, err
+		}
+		uuids[i] = u
+	}
+	return uuids, nil
+}
+
+func checkBatchNum(ctx context.Context, num uint32) error {
+	if num == 0 || num > MaxNextIdsNum {
+		zap.S().Errorf("取id超过限制的数量或等于0, maxIdNum:%v, currentIdNum:%v", MaxNextIdsNum, num)
+		return errors.New(fmt.Sprintf("数量参数不对: %d", num))
+	}
+	return ctx.Err()
+}
+
+// 生成一个ULID，调用方必须持有mutex
+func (id *IdWorker) nextUlid(ctx context.Context) (Ulid, error) {
+	lastMillis := id.lastUlid.millis()
+	timestamp, err := id.timeGenSince(ctx, lastMillis, time.Millisecond)
+	if err != nil {
+		return Ulid{}, err
+	}
+
+	u := id.lastUlid
+	if timestamp != lastMillis || !u.increment() {
+		if timestamp == lastMillis {
+			// 随机数部分已经递增到最大值，等待进入下一毫秒
+			traceSequenceExhausted(ctx)
+			if timestamp, err = id.waitUntil(ctx, lastMillis+1, time.Millisecond); err != nil {
+				return Ulid{}, err
+			}
+		}
+		if u, err = newUlid(timestamp); err != nil {
+			return Ulid{}, err
+		}
+	}
+	id.lastUlid = u
+	return u, nil
+}
+
+// 生成一个UUIDv7，调用方必须持有mutex
+func (id *IdWorker) nextUuid(ctx context.Context) (Uuid, error) {
+	lastMillis := id.lastUuid.millis()
+	timestamp, err := id.timeGenSince(ctx, lastMillis, time.Millisecond)
+	if err != nil {
+		return Uuid{}, err
+	}
+
+	counter := id.lastUuid.counter() + 1
+	if timestamp != lastMillis || counter > uuidCounterMask {
+		if timestamp == lastMillis {
+			// 计数器已经用完，等待进入下一毫秒
+			traceSequenceExhausted(ctx)
+			if timestamp, err = id.waitUntil(ctx, lastMillis+1, time.Millisecond); err != nil {
+				return Uuid{}, err
+			}
+		}
+		var seed [2]byte
+		if _, err = rand.Read(seed[:]); err != nil {
+			return Uuid{}, err
+		}
+		counter = binary.BigEndian.Uint16(seed[:]) & uuidCounterSeedMask
+	}
+
+	u, err := newUuid(timestamp, counter)
+	if err != nil {
+		return Uuid{}, err
+	}
+	id.lastUuid = u
+	return u, nil
+}
+
+func newUlid(timestamp int64) (u Ulid, err error) {
+	putMillis(u[:], timestamp)
+	_, err = rand.Read(u[6:])
+	return
+}
+
+func newUuid(timestamp int64, counter uint16) (u Uuid, err error) {
+	putMillis(u[:], timestamp)
+	if _, err = rand.Read(u[8:]); err != nil {
+		return
+	}
+	binary.BigEndian.PutUint16(u[6:8], 0x7000|counter&uuidCounterMask)
+	u[8] = u[8]&0x3f | 0x80
+	return
+}
+
+func putMillis(b []byte, timestamp int64) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(timestamp))
+	copy(b[:6], buf[2:])
+}
+
+func getMillis(b []byte) int64 {
+	var buf [8]byte
+	copy(buf[2:], b[:6])
+	return int64(binary.BigEndian.Uint64(buf[:]))
+}
+
+// 随机数部分加1，溢出时返回false
+func (u *Ulid) increment() bool {
+	for i := len(u) - 1; i >= 6; i-- {
+		u[i]++
+		if u[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (u Ulid) millis() int64 {
+	return getMillis(u[:])
+}
+
+func (u Ulid) Time() time.Time {
+	return time.Unix(0, u.millis()*int64(time.Millisecond))
+}
+
+// 26位的crockford base32编码
+func (u Ulid) String() string {
+	hi, lo := binary.BigEndian.Uint64(u[:8]), binary.BigEndian.Uint64(u[8:])
+	buf := make([]byte, 26)
+	for i := len(buf) - 1; i >= 0; i-- {
+		buf[i] = crockfordBase32Alphabet[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(buf)
+}
+
+func (u Uuid) millis() int64 {
+	return getMillis(u[:])
+}
+
+func (u Uuid) counter() uint16 {
+	return binary.BigEndian.Uint16(u[6:8]) & uuidCounterMask
+}
+
+func (u Uuid) Time() time.Time {
+	return time.Unix(0, u.millis()*int64(time.Millisecond))
+}
+
+// xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx格式
+func (u Uuid) String() string {
+	buf := make([]byte, 36)
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf)
+}
@@ -0,0 +1,122 @@
+package model
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var (
+	ulidPattern = regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+	uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+)
+
+func TestUlid_String(t *testing.T) {
+	var u Ulid
+	if s := u.String(); s != "00000000000000000000000000" {
+		t.Errorf("全0的ULID编码不正确: %s", s)
+	}
+	for i := range u {
+		u[i] = 0xff
+	}
+	if s := u.String(); s != "7ZZZZZZZZZZZZZZZZZZZZZZZZZ" {
+		t.Errorf("全1的ULID编码不正确: %s", s)
+	}
+	u = Ulid{0x01, 0x56, 0x3d, 0xf3, 0x64, 0x81}
+	if s := u.String(); s[:10] != "01ARYZ6S41" {
+		t.Errorf("ULID时间戳编码不正确: %s", s)
+	}
+}
+
+func TestIdWorker_NextUlids(t *testing.T) {
+	now := testTwepoch.Add(time.Second)
+	clock := NewFakeClock(now)
+	idWorker := newTestIdWorker(t, clock)
+
+	ulids, err := idWorker.NextUlidsWithContext(context.Background(), MaxNextIdsNum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.Add(time.Millisecond)
+	more, err := idWorker.NextUlidsWithContext(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ulids = append(ulids, more...)
+
+	for i, u := range ulids {
+		if !ulidPattern.MatchString(u.String()) {
+			t.Fatalf("ULID格式不正确: %s", u)
+		}
+		if i > 0 && (bytes.Compare(u[:], ulids[i-1][:]) <= 0 || u.String() <= ulids[i-1].String()) {
+			t.Fatalf("ULID应该严格递增, %s, %s", ulids[i-1], u)
+		}
+	}
+	if !ulids[0].Time().Equal(now) || !ulids[len(ulids)-1].Time().Equal(now.Add(time.Millisecond)) {
+		t.Errorf("ULID时间戳不正确, %v, %v", ulids[0].Time(), ulids[len(ulids)-1].Time())
+	}
+
+	clock.Add(-time.Second)
+	if _, err = idWorker.NextUlidsWithContext(context.Background(), 1); err == nil {
+		t.Error("时钟大幅回拨时应该返回错误")
+	}
+	if _, err = idWorker.NextUlidsWithContext(context.Background(), 0); err == nil {
+		t.Error("数量为0时应该返回错误")
+	}
+}
+
+func TestIdWorker_NextUlidsOverflow(t *testing.T) {
+	now := testTwepoch.Add(time.Second)
+	idWorker := newTestIdWorker(t, NewFakeClock(now))
+
+	// 随机数部分已经是最大值，下一个ULID只能进入下一毫秒
+	putMillis(idWorker.lastUlid[:], now.UnixNano()/int64(time.Millisecond))
+	for i := 6; i < len(idWorker.lastUlid); i++ {
+		idWorker.lastUlid[i] = 0xff
+	}
+	ulids, err := idWorker.NextUlidsWithContext(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ulids[0].Time().Equal(now.Add(time.Millisecond)) {
+		t.Errorf("随机数部分溢出后应该进入下一毫秒, time:%v", ulids[0].Time())
+	}
+}
+
+func TestIdWorker_NextUuids(t *testing.T) {
+	now := testTwepoch.Add(time.Second)
+	clock := NewFakeClock(now)
+	idWorker := newTestIdWorker(t, clock)
+
+	var uuids []Uuid
+	// 计数器最多从0x7ff开始，5000个一定会用完当前毫秒
+	for len(uuids) < 5000 {
+		batch, err := idWorker.NextUuidsWithContext(context.Background(), MaxNextIdsNum)
+		if err != nil {
+			t.Fatal(err)
+		}
+		uuids = append(uuids, batch...)
+	}
+
+	for i, u := range uuids {
+		if !uuidPattern.MatchString(u.String()) {
+			t.Fatalf("UUIDv7格式不正确: %s", u)
+		}
+		if i > 0 && (bytes.Compare(u[:], uuids[i-1][:]) <= 0 || u.String() <= uuids[i-1].String()) {
+			t.Fatalf("UUIDv7应该严格递增, %s, %s", uuids[i-1], u)
+		}
+	}
+	if !uuids[0].Time().Equal(now) {
+		t.Errorf("UUIDv7时间戳不正确: %v", uuids[0].Time())
+	}
+	if last := uuids[len(uuids)-1].Time(); !last.After(now) {
+		t.Errorf("计数器用完后应该进入下一毫秒: %v", last)
+	}
+
+	clock.Set(now.Add(-time.Second))
+	if _, err := idWorker.NextUuidsWithContext(context.Background(), 1); err == nil {
+		t.Error("时钟大幅回拨时应该返回错误")
+	}
+}
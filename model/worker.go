@@ -2,77 +2,336 @@ package model
 
 import (
 	"errors"
+	"fmt"
 	"github.com/LazzyQ/msnowflake/basic"
 	"go.uber.org/zap"
-	"strconv"
-	"strings"
+	"math/rand"
 	"sync"
 	"time"
 )
 
 var (
-	worker *IdWorker
+	worker       *IdWorker
+	registration *Registration
 )
 
 type IdWorker struct {
-	sequence      int64
-	lastTimestamp int64
-	workerId      int64
-	twepoch       int64 // 起始时间
-	dataCenterId  int64
-	mutex         sync.Mutex
+	sequence       int64
+	sequenceOffset int64 // 当前时间单位的sequence起始值
+	sequenceStart  SequenceStart
+	rand           *rand.Rand
+	lastTimestamp  int64
+	firstTimestamp int64 // 第一个snowflake id的时间戳，未发号时为-1
+	workerId       int64
+	twepoch        int64 // 起始时间，单位与layout的时间单位一致
+	maxTimestamp   int64 // 扣除安全预留后允许的最大timestamp
+	dataCenterId   int64
+	layout         Layout
+	twepochTime    time.Time
+	safetyMargin   time.Duration
+	lastUlid       Ulid // 上一个ULID，同一毫秒内在它的基础上递增
+	lastUuid       Uuid // 上一个UUIDv7，同一毫秒内在它的计数器上递增
+	clock          Clock
+	obfuscator     *Obfuscator // 为nil时不混淆
+	journal        *Journal    // 为nil时不记录审计日志
+	disabled       []error     // 停止发号的原因，不为空时拒绝发号
+	mutex          sync.Mutex
 }
 
-func InitIdWorker(config basic.SnowflakeConfig) (*IdWorker, error) {
-	dataCenterId := config.GetDataCenter()
-	workerId := config.GetWorkerId()
-	twepoch, err := config.GetTwepoch()
-	if err != nil {
-		return nil, err
+// IdWorker的可选配置
+type Option func(*IdWorker)
+
+// 指定时钟，默认使用系统时钟
+func WithClock(clock Clock) Option {
+	return func(id *IdWorker) {
+		id.clock = clock
+	}
+}
+
+// 指定id的位布局，默认为DefaultLayout
+func WithLayout(layout Layout) Option {
+	return func(id *IdWorker) {
+		id.layout = layout
+	}
+}
+
+// timestamp耗尽前预留的时间，进入预留期后拒绝发号
+func WithSafetyMargin(margin time.Duration) Option {
+	return func(id *IdWorker) {
+		id.safetyMargin = margin
 	}
+}
+
+// 对生成的id做可逆的混淆，隐藏发号量
+func WithObfuscator(obfuscator *Obfuscator) Option {
+	return func(id *IdWorker) {
+		id.obfuscator = obfuscator
+	}
+}
+
+// 每个时间单位的sequence起始值，默认从0开始。不从0开始时同一时间单位内的id不再单调递增，
+// 不同时间单位之间仍然按时间递增
+func WithSequenceStart(start SequenceStart) Option {
+	return func(id *IdWorker) {
+		id.sequenceStart = start
+	}
+}
+
+// 把发出的id区间写入审计日志
+func WithJournal(journal *Journal) Option {
+	return func(id *IdWorker) {
+		id.journal = journal
+	}
+}
 
-	idWorker := &IdWorker{}
-	if workerId > maxWorkerId || workerId < 0 {
-		zap.S().Errorw("workerId必须在区间内", "upper", maxWorkerId, "lower", 0)
+// 创建一个IdWorker，不做etcd注册
+func NewIdWorker(dataCenterId, workerId int64, twepoch time.Time, opts ...Option) (*IdWorker, error) {
+	idWorker := &IdWorker{
+		workerId:       workerId,
+		dataCenterId:   dataCenterId,
+		lastTimestamp:  -1,
+		firstTimestamp: -1,
+		sequence:       0,
+		layout:         DefaultLayout,
+		clock:          systemClock{},
+		rand:           newRand(),
+	}
+	for _, o := range opts {
+		o(idWorker)
+	}
+
+	layout := idWorker.layout
+	if err := layout.Validate(); err != nil {
+		zap.S().Errorw("layout配置不正确", "layout", layout, "err", err)
+		return nil, err
+	}
+	if workerId > layout.MaxWorkerId() || workerId < 0 {
+		zap.S().Errorw("workerId必须在区间内", "upper", layout.MaxWorkerId(), "lower", 0)
 		return nil, errors.New("workerId超过限制")
 	}
-	if dataCenterId > maxDataCenterId || dataCenterId < 0 {
-		zap.S().Errorw("dataCenterId超过限制", "upper", maxDataCenterId, "lower", 0)
+	if dataCenterId > layout.MaxDataCenterId() || dataCenterId < 0 {
+		zap.S().Errorw("dataCenterId超过限制", "upper", layout.MaxDataCenterId(), "lower", 0)
 		return nil, errors.New("dataCenterId超过限制")
 	}
+	if twepoch.After(idWorker.clock.Now()) {
+		zap.S().Errorw("twepoch不能晚于当前时间", "twepoch", twepoch)
+		return nil, errors.New("twepoch不能晚于当前时间")
+	}
+	if idWorker.sequenceStart < SequenceStartZero || idWorker.sequenceStart > SequenceStartRotating {
+		return nil, errors.New(fmt.Sprintf("不支持的sequence起始方式: %s", idWorker.sequenceStart))
+	}
+	if idWorker.safetyMargin < 0 {
+		return nil, errors.New("safetyMargin不能为负数")
+	}
+
+	// 预留时间按时间单位向上取整
+	marginUnits := int64((idWorker.safetyMargin + layout.TimeUnit - 1) / layout.TimeUnit)
+	idWorker.twepochTime = twepoch
+	idWorker.twepoch = layout.units(twepoch)
+	idWorker.maxTimestamp = layout.MaxTimestamp() - marginUnits
+	if idWorker.timeGen(layout.TimeUnit)-idWorker.twepoch > idWorker.maxTimestamp {
+		zap.S().Errorw("timestamp即将耗尽，拒绝启动",
+			"exhaustTime", layout.ExhaustTime(twepoch),
+			"safetyMargin", idWorker.safetyMargin)
+		return nil, errors.New("timestamp即将耗尽")
+	}
+	return idWorker, nil
+}
 
-	etcd := basic.GetEtcd()
-	workerKey := strings.Join([]string{"msnowflake", "worker", strconv.FormatInt(workerId, 10)}, "/")
-	txResponse, err := etcd.TxKeepaliveWithTTL(workerKey, string(workerId), 2)
+// 按照配置创建IdWorker，不做etcd注册
+func BuildIdWorker(config basic.SnowflakeConfig, opts ...Option) (*IdWorker, error) {
+	layout, err := configLayout(config)
+	if err != nil {
+		zap.S().Errorw("Snowflake的Layout配置不正确", "layout", config.GetLayout(), "err", err)
+		return nil, err
+	}
+	// 未配置twepoch时使用布局自带的起始时间
+	if len(config.Twepoch) == 0 {
+		config.Twepoch = layout.Twepoch
+	}
+	twepoch, err := config.GetTwepoch()
 	if err != nil {
 		return nil, err
 	}
 
-	if !txResponse.Success {
-		zap.S().Errorw("worker注册到etcd失败")
-		return nil, errors.New("worker注册失败")
+	sequenceStart, err := ParseSequenceStart(config.GetSequenceStart())
+	if err != nil {
+		zap.S().Errorw("Snowflake的SequenceStart配置不正确", "sequenceStart", config.GetSequenceStart(), "err", err)
+		return nil, err
 	}
 
-	idWorker.workerId = workerId
-	idWorker.dataCenterId = dataCenterId
-	idWorker.lastTimestamp = -1
-	idWorker.sequence = 0
-	idWorker.twepoch = twepoch.UnixNano() / int64(time.Millisecond)
-	idWorker.mutex = sync.Mutex{}
+	opts = append([]Option{WithLayout(layout), WithSafetyMargin(config.GetSafetyMargin()), WithSequenceStart(sequenceStart)}, opts...)
+	if key := config.GetObfuscateKey(); len(key) > 0 {
+		obfuscator, err := NewObfuscator([]byte(key))
+		if err != nil {
+			zap.S().Errorw("Snowflake的ObfuscateKey配置不正确", "err", err)
+			return nil, err
+		}
+		opts = append(opts, WithObfuscator(obfuscator))
+	}
+	return NewIdWorker(config.GetDataCenter(), config.GetWorkerId(), twepoch, opts...)
+}
+
+// 未配置layout时使用默认布局，方便嵌入使用时只填写必要的配置
+func configLayout(config basic.SnowflakeConfig) (Layout, error) {
+	if len(config.GetLayout()) == 0 {
+		return DefaultLayout, nil
+	}
+	return ParseLayout(config.GetLayout())
+}
+
+// 在etcd中注册workerId并创建IdWorker，workerId小于0时自动选择空闲的workerId
+func Register(etcd *basic.Etcd, config basic.SnowflakeConfig, opts ...Option) (*IdWorker, *Registration, error) {
+	var (
+		registration *Registration
+		err          error
+	)
+	if config.GetWorkerId() < 0 {
+		layout, err := configLayout(config)
+		if err != nil {
+			return nil, nil, err
+		}
+		if registration, err = AssignWorkerId(etcd, config, layout.MaxWorkerId()); err != nil {
+			return nil, nil, err
+		}
+		config.WorkerId = registration.WorkerId
+	}
+
+	idWorker, err := BuildIdWorker(config, opts...)
+	if err != nil {
+		if registration != nil {
+			_ = registration.Close()
+		}
+		return nil, nil, err
+	}
+	if registration == nil {
+		if registration, err = RegisterWorker(etcd, config.GetWorkerId()); err != nil {
+			return nil, nil, err
+		}
+	}
+	registration.DisableOnLost(idWorker)
+	return idWorker, registration, nil
+}
+
+func InitIdWorker(config basic.SnowflakeConfig) (*IdWorker, error) {
+	var opts []Option
+	if j := GetJournal(); j != nil {
+		opts = append(opts, WithJournal(j))
+	}
+	idWorker, r, err := Register(basic.GetEtcd(), config, opts...)
+	if err != nil {
+		return nil, err
+	}
+	registration = r
 
+	info := idWorker.Info()
+	layout := info.Layout
 	zap.S().Infow("worker启动完成...",
-		"timestamp左移", timestampLeftShift,
-		"dataCenterId位数", dataCenterIdBits,
-		"workerId位数", workerIdBits,
-		"sequence位数", sequenceBits,
-		"workerId", workerId)
+		"layout", layout,
+		"时间单位", layout.TimeUnit,
+		"timestamp位数", layout.TimestampBits,
+		"dataCenterId位数", layout.DataCenterBits,
+		"workerId位数", layout.WorkerBits,
+		"sequence位数", layout.SequenceBits,
+		"twepoch", info.Twepoch,
+		"timestamp耗尽时间", info.ExhaustTime,
+		"停止发号时间", info.ServeUntil,
+		"dataCenterId", info.DataCenterId,
+		"workerId", info.WorkerId,
+		"sequence起始方式", idWorker.sequenceStart,
+		"混淆", info.Obfuscated)
 	worker = idWorker
 	return idWorker, nil
 }
 
+// 服务停止时停止发号，停止检测冲突，并释放workerId和退出workerId分配的选举
+func CloseIdWorker() error {
+	if worker == nil {
+		return nil
+	}
+	worker.Disable(errors.New("服务已停止"))
+	if detector != nil {
+		detector.Close()
+	}
+	if registration == nil {
+		return nil
+	}
+	return registration.Close()
+}
+
+// 停止发号，之后获取id都返回最早的停止原因。多个原因同时存在时，需要每个原因都被Enable才恢复
+func (id *IdWorker) Disable(err error) {
+	id.mutex.Lock()
+	defer id.mutex.Unlock()
+	for _, cause := range id.disabled {
+		// 同一个原因(如重复收到冲突节点的更新)只记录一次
+		if cause == err || cause.Error() == err.Error() {
+			return
+		}
+	}
+	id.disabled = append(id.disabled, err)
+}
+
+// 移除停止发号的原因cause，没有其他原因时恢复发号
+func (id *IdWorker) Enable(cause error) {
+	id.mutex.Lock()
+	defer id.mutex.Unlock()
+	for i, err := range id.disabled {
+		if err == cause {
+			id.disabled = append(id.disabled[:i], id.disabled[i+1:]...)
+			return
+		}
+	}
+}
+
+type WorkerInfo struct {
+	Layout       Layout
+	DataCenterId int64
+	WorkerId     int64
+	Twepoch      time.Time
+	ExhaustTime  time.Time // timestamp字段耗尽的时间
+	ServeUntil   time.Time // 扣除安全预留后停止发号的时间
+	SafetyMargin time.Duration
+	Obfuscated   bool
+}
+
+func (id *IdWorker) Info() WorkerInfo {
+	layout := id.layout
+	return WorkerInfo{
+		Layout:       layout,
+		DataCenterId: id.dataCenterId,
+		WorkerId:     id.workerId,
+		Twepoch:      id.twepochTime,
+		ExhaustTime:  layout.ExhaustTime(id.twepochTime),
+		ServeUntil:   layout.time(id.twepoch + id.maxTimestamp + 1).In(id.twepochTime.Location()),
+		SafetyMargin: id.safetyMargin,
+		Obfuscated:   id.obfuscator != nil,
+	}
+}
+
+// 已发出的snowflake id的时间区间，两端都包含在内，还没有发号时issued为false
+func (id *IdWorker) IssuedRange() (first, last time.Time, issued bool) {
+	id.mutex.Lock()
+	defer id.mutex.Unlock()
+	if id.firstTimestamp < 0 {
+		return time.Time{}, time.Time{}, false
+	}
+	location := id.twepochTime.Location()
+	return id.layout.time(id.firstTimestamp).In(location), id.layout.time(id.lastTimestamp).In(location), true
+}
+
 func GetIdWorker() (*IdWorker, error) {
 	if worker == nil {
 		return nil, errors.New("worker未完成初始化")
 	}
 	return worker, nil
 }
+
+// 返回当前worker的布局和twepoch在[start, end]时间范围内可能生成的最小和最大id，
+// dataCenterId、workerId为AnyId时不限定。开启混淆时id不随时间递增，返回错误
+func (id *IdWorker) IdRange(start, end time.Time, dataCenterId, workerId int64) (min, max int64, err error) {
+	if id.obfuscator != nil {
+		return 0, 0, errors.New("开启id混淆时无法按时间范围计算id")
+	}
+	return IdRange(start, end, id.twepochTime, id.layout, dataCenterId, workerId)
+}
@@ -20,11 +20,51 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
+// id的字符串编码，字符串均为定长，字典序与数值大小一致
+type Encoding int32
+
+const (
+	Encoding_NONE             Encoding = 0
+	Encoding_BASE62           Encoding = 1
+	Encoding_CROCKFORD_BASE32 Encoding = 2
+	Encoding_HEX              Encoding = 3
+	Encoding_DECIMAL          Encoding = 4
+)
+
+var Encoding_name = map[int32]string{
+	0: "NONE",
+	1: "BASE62",
+	2: "CROCKFORD_BASE32",
+	3: "HEX",
+	4: "DECIMAL",
+}
+
+var Encoding_value = map[string]int32{
+	"NONE":             0,
+	"BASE62":           1,
+	"CROCKFORD_BASE32": 2,
+	"HEX":              3,
+	"DECIMAL":          4,
+}
+
+func (x Encoding) String() string {
+	return proto.EnumName(Encoding_name, int32(x))
+}
+
+func (Encoding) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_086e398f62286225, []int{0}
+}
+
+// code: 0成功，429请求过于频繁，430当日配额已用完
 type IdResponse struct {
-	Code                 int32    `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
-	Message              string   `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	Id                   int64    `protobuf:"varint,3,opt,name=id,proto3" json:"id,omitempty"`
-	Ids                  []int64  `protobuf:"varint,4,rep,packed,name=ids,proto3" json:"ids,omitempty"`
+	Code    int32    `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message string   `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Id      int64    `protobuf:"varint,3,opt,name=id,proto3" json:"id,omitempty"`
+	Ids     []int64  `protobuf:"varint,4,rep,packed,name=ids,proto3" json:"ids,omitempty"`
+	IdStr   string   `protobuf:"bytes,5,opt,name=id_str,json=idStr,proto3" json:"id_str,omitempty"`
+	IdsStr  []string `protobuf:"bytes,6,rep,name=ids_str,json=idsStr,proto3" json:"ids_str,omitempty"`
+	// 请求id，调用方在metadata的Msnowflake-Request-Id中指定时原样返回，否则由服务端生成
+	RequestId            string   `protobuf:"bytes,7,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -83,8 +123,104 @@ func (m *IdResponse) GetIds() []int64 {
 	return nil
 }
 
+func (m *IdResponse) GetIdStr() string {
+	if m != nil {
+		return m.IdStr
+	}
+	return ""
+}
+
+func (m *IdResponse) GetIdsStr() []string {
+	if m != nil {
+		return m.IdsStr
+	}
+	return nil
+}
+
+func (m *IdResponse) GetRequestId() string {
+	if m != nil {
+		return m.RequestId
+	}
+	return ""
+}
+
+// 128位id，ids为16字节的原始值，ids_str为ULID或UUID的标准字符串，code同IdResponse
+type BytesIdResponse struct {
+	Code                 int32    `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message              string   `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Ids                  [][]byte `protobuf:"bytes,3,rep,name=ids,proto3" json:"ids,omitempty"`
+	IdsStr               []string `protobuf:"bytes,4,rep,name=ids_str,json=idsStr,proto3" json:"ids_str,omitempty"`
+	RequestId            string   `protobuf:"bytes,5,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BytesIdResponse) Reset()         { *m = BytesIdResponse{} }
+func (m *BytesIdResponse) String() string { return proto.CompactTextString(m) }
+func (*BytesIdResponse) ProtoMessage()    {}
+func (*BytesIdResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_086e398f62286225, []int{1}
+}
+
+func (m *BytesIdResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BytesIdResponse.Unmarshal(m, b)
+}
+func (m *BytesIdResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BytesIdResponse.Marshal(b, m, deterministic)
+}
+func (m *BytesIdResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BytesIdResponse.Merge(m, src)
+}
+func (m *BytesIdResponse) XXX_Size() int {
+	return xxx_messageInfo_BytesIdResponse.Size(m)
+}
+func (m *BytesIdResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_BytesIdResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BytesIdResponse proto.InternalMessageInfo
+
+func (m *BytesIdResponse) GetCode() int32 {
+	if m != nil {
+		return m.Code
+	}
+	return 0
+}
+
+func (m *BytesIdResponse) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+func (m *BytesIdResponse) GetIds() [][]byte {
+	if m != nil {
+		return m.Ids
+	}
+	return nil
+}
+
+func (m *BytesIdResponse) GetIdsStr() []string {
+	if m != nil {
+		return m.IdsStr
+	}
+	return nil
+}
+
+func (m *BytesIdResponse) GetRequestId() string {
+	if m != nil {
+		return m.RequestId
+	}
+	return ""
+}
+
+// caller为调用方标识，为空时使用metadata中的Msnowflake-Caller
 type IdRequest struct {
 	Num                  uint32   `protobuf:"varint,1,opt,name=num,proto3" json:"num,omitempty"`
+	Encoding             Encoding `protobuf:"varint,2,opt,name=encoding,proto3,enum=msnowflake.Encoding" json:"encoding,omitempty"`
+	Caller               string   `protobuf:"bytes,3,opt,name=caller,proto3" json:"caller,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -94,7 +230,7 @@ func (m *IdRequest) Reset()         { *m = IdRequest{} }
 func (m *IdRequest) String() string { return proto.CompactTextString(m) }
 func (*IdRequest) ProtoMessage()    {}
 func (*IdRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_086e398f62286225, []int{1}
+	return fileDescriptor_086e398f62286225, []int{2}
 }
 
 func (m *IdRequest) XXX_Unmarshal(b []byte) error {
@@ -122,9 +258,860 @@ func (m *IdRequest) GetNum() uint32 {
 	return 0
 }
 
+func (m *IdRequest) GetEncoding() Encoding {
+	if m != nil {
+		return m.Encoding
+	}
+	return Encoding_NONE
+}
+
+func (m *IdRequest) GetCaller() string {
+	if m != nil {
+		return m.Caller
+	}
+	return ""
+}
+
+type EncodeRequest struct {
+	Ids                  []int64  `protobuf:"varint,1,rep,packed,name=ids,proto3" json:"ids,omitempty"`
+	Encoding             Encoding `protobuf:"varint,2,opt,name=encoding,proto3,enum=msnowflake.Encoding" json:"encoding,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *EncodeRequest) Reset()         { *m = EncodeRequest{} }
+func (m *EncodeRequest) String() string { return proto.CompactTextString(m) }
+func (*EncodeRequest) ProtoMessage()    {}
+func (*EncodeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_086e398f62286225, []int{3}
+}
+
+func (m *EncodeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EncodeRequest.Unmarshal(m, b)
+}
+func (m *EncodeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EncodeRequest.Marshal(b, m, deterministic)
+}
+func (m *EncodeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EncodeRequest.Merge(m, src)
+}
+func (m *EncodeRequest) XXX_Size() int {
+	return xxx_messageInfo_EncodeRequest.Size(m)
+}
+func (m *EncodeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_EncodeRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EncodeRequest proto.InternalMessageInfo
+
+func (m *EncodeRequest) GetIds() []int64 {
+	if m != nil {
+		return m.Ids
+	}
+	return nil
+}
+
+func (m *EncodeRequest) GetEncoding() Encoding {
+	if m != nil {
+		return m.Encoding
+	}
+	return Encoding_NONE
+}
+
+type EncodeResponse struct {
+	Code                 int32    `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message              string   `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	IdsStr               []string `protobuf:"bytes,3,rep,name=ids_str,json=idsStr,proto3" json:"ids_str,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *EncodeResponse) Reset()         { *m = EncodeResponse{} }
+func (m *EncodeResponse) String() string { return proto.CompactTextString(m) }
+func (*EncodeResponse) ProtoMessage()    {}
+func (*EncodeResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_086e398f62286225, []int{4}
+}
+
+func (m *EncodeResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EncodeResponse.Unmarshal(m, b)
+}
+func (m *EncodeResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EncodeResponse.Marshal(b, m, deterministic)
+}
+func (m *EncodeResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EncodeResponse.Merge(m, src)
+}
+func (m *EncodeResponse) XXX_Size() int {
+	return xxx_messageInfo_EncodeResponse.Size(m)
+}
+func (m *EncodeResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_EncodeResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EncodeResponse proto.InternalMessageInfo
+
+func (m *EncodeResponse) GetCode() int32 {
+	if m != nil {
+		return m.Code
+	}
+	return 0
+}
+
+func (m *EncodeResponse) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+func (m *EncodeResponse) GetIdsStr() []string {
+	if m != nil {
+		return m.IdsStr
+	}
+	return nil
+}
+
+type DecodeRequest struct {
+	IdsStr               []string `protobuf:"bytes,1,rep,name=ids_str,json=idsStr,proto3" json:"ids_str,omitempty"`
+	Encoding             Encoding `protobuf:"varint,2,opt,name=encoding,proto3,enum=msnowflake.Encoding" json:"encoding,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DecodeRequest) Reset()         { *m = DecodeRequest{} }
+func (m *DecodeRequest) String() string { return proto.CompactTextString(m) }
+func (*DecodeRequest) ProtoMessage()    {}
+func (*DecodeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_086e398f62286225, []int{5}
+}
+
+func (m *DecodeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DecodeRequest.Unmarshal(m, b)
+}
+func (m *DecodeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DecodeRequest.Marshal(b, m, deterministic)
+}
+func (m *DecodeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DecodeRequest.Merge(m, src)
+}
+func (m *DecodeRequest) XXX_Size() int {
+	return xxx_messageInfo_DecodeRequest.Size(m)
+}
+func (m *DecodeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DecodeRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DecodeRequest proto.InternalMessageInfo
+
+func (m *DecodeRequest) GetIdsStr() []string {
+	if m != nil {
+		return m.IdsStr
+	}
+	return nil
+}
+
+func (m *DecodeRequest) GetEncoding() Encoding {
+	if m != nil {
+		return m.Encoding
+	}
+	return Encoding_NONE
+}
+
+type DecodeResponse struct {
+	Code                 int32    `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message              string   `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Ids                  []int64  `protobuf:"varint,3,rep,packed,name=ids,proto3" json:"ids,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DecodeResponse) Reset()         { *m = DecodeResponse{} }
+func (m *DecodeResponse) String() string { return proto.CompactTextString(m) }
+func (*DecodeResponse) ProtoMessage()    {}
+func (*DecodeResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_086e398f62286225, []int{6}
+}
+
+func (m *DecodeResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DecodeResponse.Unmarshal(m, b)
+}
+func (m *DecodeResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DecodeResponse.Marshal(b, m, deterministic)
+}
+func (m *DecodeResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DecodeResponse.Merge(m, src)
+}
+func (m *DecodeResponse) XXX_Size() int {
+	return xxx_messageInfo_DecodeResponse.Size(m)
+}
+func (m *DecodeResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DecodeResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DecodeResponse proto.InternalMessageInfo
+
+func (m *DecodeResponse) GetCode() int32 {
+	if m != nil {
+		return m.Code
+	}
+	return 0
+}
+
+func (m *DecodeResponse) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+func (m *DecodeResponse) GetIds() []int64 {
+	if m != nil {
+		return m.Ids
+	}
+	return nil
+}
+
+type RevealRequest struct {
+	Ids                  []int64  `protobuf:"varint,1,rep,packed,name=ids,proto3" json:"ids,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RevealRequest) Reset()         { *m = RevealRequest{} }
+func (m *RevealRequest) String() string { return proto.CompactTextString(m) }
+func (*RevealRequest) ProtoMessage()    {}
+func (*RevealRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_086e398f62286225, []int{7}
+}
+
+func (m *RevealRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RevealRequest.Unmarshal(m, b)
+}
+func (m *RevealRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RevealRequest.Marshal(b, m, deterministic)
+}
+func (m *RevealRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RevealRequest.Merge(m, src)
+}
+func (m *RevealRequest) XXX_Size() int {
+	return xxx_messageInfo_RevealRequest.Size(m)
+}
+func (m *RevealRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RevealRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RevealRequest proto.InternalMessageInfo
+
+func (m *RevealRequest) GetIds() []int64 {
+	if m != nil {
+		return m.Ids
+	}
+	return nil
+}
+
+type RevealResponse struct {
+	Code                 int32    `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message              string   `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Ids                  []int64  `protobuf:"varint,3,rep,packed,name=ids,proto3" json:"ids,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RevealResponse) Reset()         { *m = RevealResponse{} }
+func (m *RevealResponse) String() string { return proto.CompactTextString(m) }
+func (*RevealResponse) ProtoMessage()    {}
+func (*RevealResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_086e398f62286225, []int{8}
+}
+
+func (m *RevealResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RevealResponse.Unmarshal(m, b)
+}
+func (m *RevealResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RevealResponse.Marshal(b, m, deterministic)
+}
+func (m *RevealResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RevealResponse.Merge(m, src)
+}
+func (m *RevealResponse) XXX_Size() int {
+	return xxx_messageInfo_RevealResponse.Size(m)
+}
+func (m *RevealResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_RevealResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RevealResponse proto.InternalMessageInfo
+
+func (m *RevealResponse) GetCode() int32 {
+	if m != nil {
+		return m.Code
+	}
+	return 0
+}
+
+func (m *RevealResponse) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+func (m *RevealResponse) GetIds() []int64 {
+	if m != nil {
+		return m.Ids
+	}
+	return nil
+}
+
+type InfoRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *InfoRequest) Reset()         { *m = InfoRequest{} }
+func (m *InfoRequest) String() string { return proto.CompactTextString(m) }
+func (*InfoRequest) ProtoMessage()    {}
+func (*InfoRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_086e398f62286225, []int{9}
+}
+
+func (m *InfoRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_InfoRequest.Unmarshal(m, b)
+}
+func (m *InfoRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_InfoRequest.Marshal(b, m, deterministic)
+}
+func (m *InfoRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InfoRequest.Merge(m, src)
+}
+func (m *InfoRequest) XXX_Size() int {
+	return xxx_messageInfo_InfoRequest.Size(m)
+}
+func (m *InfoRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_InfoRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InfoRequest proto.InternalMessageInfo
+
+// 时间均为unix毫秒
+type InfoResponse struct {
+	Code                 int32    `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message              string   `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Layout               string   `protobuf:"bytes,3,opt,name=layout,proto3" json:"layout,omitempty"`
+	TimeUnitMs           int64    `protobuf:"varint,4,opt,name=time_unit_ms,json=timeUnitMs,proto3" json:"time_unit_ms,omitempty"`
+	TimestampBits        uint32   `protobuf:"varint,5,opt,name=timestamp_bits,json=timestampBits,proto3" json:"timestamp_bits,omitempty"`
+	DatacenterBits       uint32   `protobuf:"varint,6,opt,name=datacenter_bits,json=datacenterBits,proto3" json:"datacenter_bits,omitempty"`
+	WorkerBits           uint32   `protobuf:"varint,7,opt,name=worker_bits,json=workerBits,proto3" json:"worker_bits,omitempty"`
+	SequenceBits         uint32   `protobuf:"varint,8,opt,name=sequence_bits,json=sequenceBits,proto3" json:"sequence_bits,omitempty"`
+	DatacenterId         int64    `protobuf:"varint,9,opt,name=datacenter_id,json=datacenterId,proto3" json:"datacenter_id,omitempty"`
+	WorkerId             int64    `protobuf:"varint,10,opt,name=worker_id,json=workerId,proto3" json:"worker_id,omitempty"`
+	Twepoch              int64    `protobuf:"varint,11,opt,name=twepoch,proto3" json:"twepoch,omitempty"`
+	ExhaustTime          int64    `protobuf:"varint,12,opt,name=exhaust_time,json=exhaustTime,proto3" json:"exhaust_time,omitempty"`
+	ServeUntil           int64    `protobuf:"varint,13,opt,name=serve_until,json=serveUntil,proto3" json:"serve_until,omitempty"`
+	Obfuscated           bool     `protobuf:"varint,14,opt,name=obfuscated,proto3" json:"obfuscated,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *InfoResponse) Reset()         { *m = InfoResponse{} }
+func (m *InfoResponse) String() string { return proto.CompactTextString(m) }
+func (*InfoResponse) ProtoMessage()    {}
+func (*InfoResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_086e398f62286225, []int{10}
+}
+
+func (m *InfoResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_InfoResponse.Unmarshal(m, b)
+}
+func (m *InfoResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_InfoResponse.Marshal(b, m, deterministic)
+}
+func (m *InfoResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InfoResponse.Merge(m, src)
+}
+func (m *InfoResponse) XXX_Size() int {
+	return xxx_messageInfo_InfoResponse.Size(m)
+}
+func (m *InfoResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_InfoResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InfoResponse proto.InternalMessageInfo
+
+func (m *InfoResponse) GetCode() int32 {
+	if m != nil {
+		return m.Code
+	}
+	return 0
+}
+
+func (m *InfoResponse) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+func (m *InfoResponse) GetLayout() string {
+	if m != nil {
+		return m.Layout
+	}
+	return ""
+}
+
+func (m *InfoResponse) GetTimeUnitMs() int64 {
+	if m != nil {
+		return m.TimeUnitMs
+	}
+	return 0
+}
+
+func (m *InfoResponse) GetTimestampBits() uint32 {
+	if m != nil {
+		return m.TimestampBits
+	}
+	return 0
+}
+
+func (m *InfoResponse) GetDatacenterBits() uint32 {
+	if m != nil {
+		return m.DatacenterBits
+	}
+	return 0
+}
+
+func (m *InfoResponse) GetWorkerBits() uint32 {
+	if m != nil {
+		return m.WorkerBits
+	}
+	return 0
+}
+
+func (m *InfoResponse) GetSequenceBits() uint32 {
+	if m != nil {
+		return m.SequenceBits
+	}
+	return 0
+}
+
+func (m *InfoResponse) GetDatacenterId() int64 {
+	if m != nil {
+		return m.DatacenterId
+	}
+	return 0
+}
+
+func (m *InfoResponse) GetWorkerId() int64 {
+	if m != nil {
+		return m.WorkerId
+	}
+	return 0
+}
+
+func (m *InfoResponse) GetTwepoch() int64 {
+	if m != nil {
+		return m.Twepoch
+	}
+	return 0
+}
+
+func (m *InfoResponse) GetExhaustTime() int64 {
+	if m != nil {
+		return m.ExhaustTime
+	}
+	return 0
+}
+
+func (m *InfoResponse) GetServeUntil() int64 {
+	if m != nil {
+		return m.ServeUntil
+	}
+	return 0
+}
+
+func (m *InfoResponse) GetObfuscated() bool {
+	if m != nil {
+		return m.Obfuscated
+	}
+	return false
+}
+
+// 时间为unix毫秒，两端都包含在内。has_datacenter_id、has_worker_id为false时不限定
+type RangeRequest struct {
+	StartTime            int64    `protobuf:"varint,1,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	EndTime              int64    `protobuf:"varint,2,opt,name=end_time,json=endTime,proto3" json:"end_time,omitempty"`
+	HasDatacenterId      bool     `protobuf:"varint,3,opt,name=has_datacenter_id,json=hasDatacenterId,proto3" json:"has_datacenter_id,omitempty"`
+	DatacenterId         int64    `protobuf:"varint,4,opt,name=datacenter_id,json=datacenterId,proto3" json:"datacenter_id,omitempty"`
+	HasWorkerId          bool     `protobuf:"varint,5,opt,name=has_worker_id,json=hasWorkerId,proto3" json:"has_worker_id,omitempty"`
+	WorkerId             int64    `protobuf:"varint,6,opt,name=worker_id,json=workerId,proto3" json:"worker_id,omitempty"`
+	Encoding             Encoding `protobuf:"varint,7,opt,name=encoding,proto3,enum=msnowflake.Encoding" json:"encoding,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RangeRequest) Reset()         { *m = RangeRequest{} }
+func (m *RangeRequest) String() string { return proto.CompactTextString(m) }
+func (*RangeRequest) ProtoMessage()    {}
+func (*RangeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_086e398f62286225, []int{11}
+}
+
+func (m *RangeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RangeRequest.Unmarshal(m, b)
+}
+func (m *RangeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RangeRequest.Marshal(b, m, deterministic)
+}
+func (m *RangeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RangeRequest.Merge(m, src)
+}
+func (m *RangeRequest) XXX_Size() int {
+	return xxx_messageInfo_RangeRequest.Size(m)
+}
+func (m *RangeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RangeRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RangeRequest proto.InternalMessageInfo
+
+func (m *RangeRequest) GetStartTime() int64 {
+	if m != nil {
+		return m.StartTime
+	}
+	return 0
+}
+
+func (m *RangeRequest) GetEndTime() int64 {
+	if m != nil {
+		return m.EndTime
+	}
+	return 0
+}
+
+func (m *RangeRequest) GetHasDatacenterId() bool {
+	if m != nil {
+		return m.HasDatacenterId
+	}
+	return false
+}
+
+func (m *RangeRequest) GetDatacenterId() int64 {
+	if m != nil {
+		return m.DatacenterId
+	}
+	return 0
+}
+
+func (m *RangeRequest) GetHasWorkerId() bool {
+	if m != nil {
+		return m.HasWorkerId
+	}
+	return false
+}
+
+func (m *RangeRequest) GetWorkerId() int64 {
+	if m != nil {
+		return m.WorkerId
+	}
+	return 0
+}
+
+func (m *RangeRequest) GetEncoding() Encoding {
+	if m != nil {
+		return m.Encoding
+	}
+	return Encoding_NONE
+}
+
+type RangeResponse struct {
+	Code                 int32    `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message              string   `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	MinId                int64    `protobuf:"varint,3,opt,name=min_id,json=minId,proto3" json:"min_id,omitempty"`
+	MaxId                int64    `protobuf:"varint,4,opt,name=max_id,json=maxId,proto3" json:"max_id,omitempty"`
+	MinIdStr             string   `protobuf:"bytes,5,opt,name=min_id_str,json=minIdStr,proto3" json:"min_id_str,omitempty"`
+	MaxIdStr             string   `protobuf:"bytes,6,opt,name=max_id_str,json=maxIdStr,proto3" json:"max_id_str,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RangeResponse) Reset()         { *m = RangeResponse{} }
+func (m *RangeResponse) String() string { return proto.CompactTextString(m) }
+func (*RangeResponse) ProtoMessage()    {}
+func (*RangeResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_086e398f62286225, []int{12}
+}
+
+func (m *RangeResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RangeResponse.Unmarshal(m, b)
+}
+func (m *RangeResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RangeResponse.Marshal(b, m, deterministic)
+}
+func (m *RangeResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RangeResponse.Merge(m, src)
+}
+func (m *RangeResponse) XXX_Size() int {
+	return xxx_messageInfo_RangeResponse.Size(m)
+}
+func (m *RangeResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_RangeResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RangeResponse proto.InternalMessageInfo
+
+func (m *RangeResponse) GetCode() int32 {
+	if m != nil {
+		return m.Code
+	}
+	return 0
+}
+
+func (m *RangeResponse) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+func (m *RangeResponse) GetMinId() int64 {
+	if m != nil {
+		return m.MinId
+	}
+	return 0
+}
+
+func (m *RangeResponse) GetMaxId() int64 {
+	if m != nil {
+		return m.MaxId
+	}
+	return 0
+}
+
+func (m *RangeResponse) GetMinIdStr() string {
+	if m != nil {
+		return m.MinIdStr
+	}
+	return ""
+}
+
+func (m *RangeResponse) GetMaxIdStr() string {
+	if m != nil {
+		return m.MaxIdStr
+	}
+	return ""
+}
+
+type StatsRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StatsRequest) Reset()         { *m = StatsRequest{} }
+func (m *StatsRequest) String() string { return proto.CompactTextString(m) }
+func (*StatsRequest) ProtoMessage()    {}
+func (*StatsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_086e398f62286225, []int{13}
+}
+
+func (m *StatsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StatsRequest.Unmarshal(m, b)
+}
+func (m *StatsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StatsRequest.Marshal(b, m, deterministic)
+}
+func (m *StatsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StatsRequest.Merge(m, src)
+}
+func (m *StatsRequest) XXX_Size() int {
+	return xxx_messageInfo_StatsRequest.Size(m)
+}
+func (m *StatsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_StatsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StatsRequest proto.InternalMessageInfo
+
+// rate、burst、daily_quota为0时不限制，quota_used为当天(UTC)已使用的配额
+type CallerStats struct {
+	Caller               string   `protobuf:"bytes,1,opt,name=caller,proto3" json:"caller,omitempty"`
+	Rate                 float64  `protobuf:"fixed64,2,opt,name=rate,proto3" json:"rate,omitempty"`
+	Burst                int64    `protobuf:"varint,3,opt,name=burst,proto3" json:"burst,omitempty"`
+	DailyQuota           int64    `protobuf:"varint,4,opt,name=daily_quota,json=dailyQuota,proto3" json:"daily_quota,omitempty"`
+	Issued               int64    `protobuf:"varint,5,opt,name=issued,proto3" json:"issued,omitempty"`
+	RateLimited          int64    `protobuf:"varint,6,opt,name=rate_limited,json=rateLimited,proto3" json:"rate_limited,omitempty"`
+	QuotaExceeded        int64    `protobuf:"varint,7,opt,name=quota_exceeded,json=quotaExceeded,proto3" json:"quota_exceeded,omitempty"`
+	QuotaUsed            int64    `protobuf:"varint,8,opt,name=quota_used,json=quotaUsed,proto3" json:"quota_used,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CallerStats) Reset()         { *m = CallerStats{} }
+func (m *CallerStats) String() string { return proto.CompactTextString(m) }
+func (*CallerStats) ProtoMessage()    {}
+func (*CallerStats) Descriptor() ([]byte, []int) {
+	return fileDescriptor_086e398f62286225, []int{14}
+}
+
+func (m *CallerStats) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CallerStats.Unmarshal(m, b)
+}
+func (m *CallerStats) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CallerStats.Marshal(b, m, deterministic)
+}
+func (m *CallerStats) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CallerStats.Merge(m, src)
+}
+func (m *CallerStats) XXX_Size() int {
+	return xxx_messageInfo_CallerStats.Size(m)
+}
+func (m *CallerStats) XXX_DiscardUnknown() {
+	xxx_messageInfo_CallerStats.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CallerStats proto.InternalMessageInfo
+
+func (m *CallerStats) GetCaller() string {
+	if m != nil {
+		return m.Caller
+	}
+	return ""
+}
+
+func (m *CallerStats) GetRate() float64 {
+	if m != nil {
+		return m.Rate
+	}
+	return 0
+}
+
+func (m *CallerStats) GetBurst() int64 {
+	if m != nil {
+		return m.Burst
+	}
+	return 0
+}
+
+func (m *CallerStats) GetDailyQuota() int64 {
+	if m != nil {
+		return m.DailyQuota
+	}
+	return 0
+}
+
+func (m *CallerStats) GetIssued() int64 {
+	if m != nil {
+		return m.Issued
+	}
+	return 0
+}
+
+func (m *CallerStats) GetRateLimited() int64 {
+	if m != nil {
+		return m.RateLimited
+	}
+	return 0
+}
+
+func (m *CallerStats) GetQuotaExceeded() int64 {
+	if m != nil {
+		return m.QuotaExceeded
+	}
+	return 0
+}
+
+func (m *CallerStats) GetQuotaUsed() int64 {
+	if m != nil {
+		return m.QuotaUsed
+	}
+	return 0
+}
+
+type StatsResponse struct {
+	Code                 int32          `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message              string         `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Callers              []*CallerStats `protobuf:"bytes,3,rep,name=callers,proto3" json:"callers,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *StatsResponse) Reset()         { *m = StatsResponse{} }
+func (m *StatsResponse) String() string { return proto.CompactTextString(m) }
+func (*StatsResponse) ProtoMessage()    {}
+func (*StatsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_086e398f62286225, []int{15}
+}
+
+func (m *StatsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StatsResponse.Unmarshal(m, b)
+}
+func (m *StatsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StatsResponse.Marshal(b, m, deterministic)
+}
+func (m *StatsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StatsResponse.Merge(m, src)
+}
+func (m *StatsResponse) XXX_Size() int {
+	return xxx_messageInfo_StatsResponse.Size(m)
+}
+func (m *StatsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_StatsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StatsResponse proto.InternalMessageInfo
+
+func (m *StatsResponse) GetCode() int32 {
+	if m != nil {
+		return m.Code
+	}
+	return 0
+}
+
+func (m *StatsResponse) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+func (m *StatsResponse) GetCallers() []*CallerStats {
+	if m != nil {
+		return m.Callers
+	}
+	return nil
+}
+
 func init() {
+	proto.RegisterEnum("msnowflake.Encoding", Encoding_name, Encoding_value)
 	proto.RegisterType((*IdResponse)(nil), "msnowflake.IdResponse")
+	proto.RegisterType((*BytesIdResponse)(nil), "msnowflake.BytesIdResponse")
 	proto.RegisterType((*IdRequest)(nil), "msnowflake.IdRequest")
+	proto.RegisterType((*EncodeRequest)(nil), "msnowflake.EncodeRequest")
+	proto.RegisterType((*EncodeResponse)(nil), "msnowflake.EncodeResponse")
+	proto.RegisterType((*DecodeRequest)(nil), "msnowflake.DecodeRequest")
+	proto.RegisterType((*DecodeResponse)(nil), "msnowflake.DecodeResponse")
+	proto.RegisterType((*RevealRequest)(nil), "msnowflake.RevealRequest")
+	proto.RegisterType((*RevealResponse)(nil), "msnowflake.RevealResponse")
+	proto.RegisterType((*InfoRequest)(nil), "msnowflake.InfoRequest")
+	proto.RegisterType((*InfoResponse)(nil), "msnowflake.InfoResponse")
+	proto.RegisterType((*RangeRequest)(nil), "msnowflake.RangeRequest")
+	proto.RegisterType((*RangeResponse)(nil), "msnowflake.RangeResponse")
+	proto.RegisterType((*StatsRequest)(nil), "msnowflake.StatsRequest")
+	proto.RegisterType((*CallerStats)(nil), "msnowflake.CallerStats")
+	proto.RegisterType((*StatsResponse)(nil), "msnowflake.StatsResponse")
 }
 
 func init() {
@@ -132,18 +1119,72 @@ func init() {
 }
 
 var fileDescriptor_086e398f62286225 = []byte{
-	// 203 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x12, 0x2d, 0x28, 0xca, 0x2f,
-	0xc9, 0xd7, 0x2f, 0xce, 0xcb, 0x2f, 0x4f, 0xcb, 0x49, 0xcc, 0x4e, 0xd5, 0x03, 0xf3, 0x85, 0xb8,
-	0x72, 0xe1, 0x22, 0x4a, 0x31, 0x5c, 0x5c, 0x9e, 0x29, 0x41, 0xa9, 0xc5, 0x05, 0xf9, 0x79, 0xc5,
-	0xa9, 0x42, 0x42, 0x5c, 0x2c, 0xc9, 0xf9, 0x29, 0xa9, 0x12, 0x8c, 0x0a, 0x8c, 0x1a, 0xac, 0x41,
-	0x60, 0xb6, 0x90, 0x04, 0x17, 0x7b, 0x6e, 0x6a, 0x71, 0x71, 0x62, 0x7a, 0xaa, 0x04, 0x93, 0x02,
-	0xa3, 0x06, 0x67, 0x10, 0x8c, 0x2b, 0xc4, 0xc7, 0xc5, 0x94, 0x99, 0x22, 0xc1, 0xac, 0xc0, 0xa8,
-	0xc1, 0x1c, 0xc4, 0x94, 0x99, 0x22, 0x24, 0xc0, 0xc5, 0x9c, 0x99, 0x52, 0x2c, 0xc1, 0xa2, 0xc0,
-	0xac, 0xc1, 0x1c, 0x04, 0x62, 0x2a, 0xc9, 0x72, 0x71, 0x82, 0x4c, 0x2f, 0x2c, 0x4d, 0x2d, 0x2e,
-	0x01, 0x49, 0xe7, 0x95, 0xe6, 0x82, 0xcd, 0xe6, 0x0d, 0x02, 0x31, 0x8d, 0x9a, 0x19, 0xb9, 0xb8,
-	0x7c, 0x83, 0x61, 0x6e, 0x11, 0xb2, 0xe4, 0x62, 0xf3, 0x4b, 0xad, 0x28, 0xf1, 0x4c, 0x11, 0x12,
-	0xd5, 0x43, 0x38, 0x51, 0x0f, 0x6e, 0x82, 0x94, 0x18, 0xba, 0x30, 0xc4, 0xd9, 0x4a, 0x0c, 0x42,
-	0x56, 0x5c, 0xec, 0x10, 0xad, 0xc5, 0x24, 0xeb, 0x4d, 0x62, 0x03, 0x87, 0x8a, 0x31, 0x20, 0x00,
-	0x00, 0xff, 0xff, 0x09, 0x6a, 0x56, 0x15, 0x2e, 0x01, 0x00, 0x00,
+	// 1066 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x56, 0xdd, 0x6e, 0xe3, 0xc4,
+	0x17, 0x5f, 0xc7, 0x89, 0x93, 0x9c, 0xc4, 0x69, 0xfe, 0xa3, 0xb6, 0xeb, 0x66, 0xff, 0x0b, 0x59,
+	0x23, 0x44, 0xb4, 0x17, 0x05, 0xba, 0x12, 0x12, 0x20, 0x21, 0xb5, 0x4d, 0x10, 0x11, 0xfd, 0x80,
+	0x29, 0xd5, 0x22, 0x6e, 0xac, 0x69, 0x66, 0xda, 0x8e, 0x36, 0xb6, 0xbb, 0x9e, 0xf1, 0x36, 0x7d,
+	0x04, 0x24, 0x24, 0x1e, 0x83, 0x0b, 0x5e, 0x85, 0x97, 0xe1, 0x0d, 0xd0, 0xcc, 0xd8, 0xc9, 0xa4,
+	0x1f, 0x88, 0x06, 0xee, 0x7c, 0x7e, 0xe7, 0x73, 0x7e, 0x73, 0xce, 0x19, 0xc3, 0xc6, 0x55, 0x96,
+	0xca, 0xf4, 0x63, 0x91, 0xa4, 0xd7, 0xe7, 0x53, 0xf2, 0x86, 0x6d, 0x6b, 0x19, 0x41, 0x3c, 0x47,
+	0xc2, 0xdf, 0x1d, 0x80, 0x31, 0xc5, 0x4c, 0x5c, 0xa5, 0x89, 0x60, 0x08, 0x41, 0x75, 0x92, 0x52,
+	0x16, 0x38, 0x7d, 0x67, 0x50, 0xc3, 0xfa, 0x1b, 0x05, 0x50, 0x8f, 0x99, 0x10, 0xe4, 0x82, 0x05,
+	0x95, 0xbe, 0x33, 0x68, 0xe2, 0x52, 0x44, 0x1d, 0xa8, 0x70, 0x1a, 0xb8, 0x7d, 0x67, 0xe0, 0xe2,
+	0x0a, 0xa7, 0xa8, 0x0b, 0x2e, 0xa7, 0x22, 0xa8, 0xf6, 0xdd, 0x81, 0x8b, 0xd5, 0x27, 0xda, 0x00,
+	0x8f, 0xd3, 0x48, 0xc8, 0x2c, 0xa8, 0x69, 0xd7, 0x1a, 0xa7, 0x27, 0x32, 0x43, 0x4f, 0xa1, 0xce,
+	0xa9, 0xd0, 0xb8, 0xd7, 0x77, 0x07, 0x4d, 0xec, 0x71, 0x2a, 0x94, 0xe2, 0x39, 0x40, 0xc6, 0xde,
+	0xe6, 0x4c, 0xc8, 0x88, 0xd3, 0xa0, 0xae, 0x7d, 0x9a, 0x05, 0x32, 0xa6, 0xe1, 0xcf, 0x0e, 0xac,
+	0xed, 0xdd, 0x48, 0x26, 0x56, 0x2e, 0xb9, 0x28, 0xd1, 0xed, 0xbb, 0x83, 0xb6, 0x29, 0xd1, 0xaa,
+	0xa5, 0xfa, 0x37, 0xb5, 0xd4, 0x6e, 0xd7, 0x72, 0x01, 0x4d, 0x55, 0x85, 0x16, 0x55, 0xd8, 0x24,
+	0x8f, 0x75, 0x0d, 0x3e, 0x56, 0x9f, 0xe8, 0x13, 0x68, 0xb0, 0x64, 0x92, 0x52, 0x9e, 0x5c, 0xe8,
+	0x1a, 0x3a, 0x3b, 0xeb, 0xdb, 0x0b, 0xde, 0xb7, 0x47, 0x85, 0x0e, 0xcf, 0xad, 0xd0, 0x26, 0x78,
+	0x13, 0x32, 0x9d, 0xb2, 0x4c, 0x33, 0xda, 0xc4, 0x85, 0x14, 0x9e, 0x80, 0xaf, 0xad, 0x99, 0x95,
+	0x4c, 0x9d, 0xc1, 0x59, 0xd0, 0xfc, 0xe8, 0x64, 0xe1, 0x6b, 0xe8, 0x94, 0x41, 0x57, 0xe2, 0xd1,
+	0x62, 0xcd, 0xb5, 0x59, 0x0b, 0x7f, 0x02, 0x7f, 0xc8, 0xec, 0x6a, 0x2d, 0x4b, 0x67, 0x89, 0xdf,
+	0xc7, 0x17, 0xfd, 0x1d, 0x74, 0x86, 0xec, 0x5f, 0x14, 0x6d, 0x5d, 0xbe, 0x21, 0x2e, 0x7c, 0x01,
+	0x3e, 0x66, 0xef, 0x18, 0x99, 0x3e, 0xc8, 0xad, 0x4a, 0x5a, 0x9a, 0xfc, 0x47, 0x49, 0x7d, 0x68,
+	0x8d, 0x93, 0xf3, 0xb4, 0x48, 0x19, 0xfe, 0xe1, 0x42, 0xdb, 0xc8, 0x2b, 0xc5, 0xdf, 0x04, 0x6f,
+	0x4a, 0x6e, 0xd2, 0x5c, 0x96, 0x6d, 0x63, 0x24, 0xd4, 0x87, 0xb6, 0xe4, 0x31, 0x8b, 0xf2, 0x84,
+	0xcb, 0x28, 0x56, 0x53, 0xa9, 0xc6, 0x14, 0x14, 0x76, 0x9a, 0x70, 0x79, 0x28, 0xd0, 0x87, 0xd0,
+	0x51, 0x92, 0x90, 0x24, 0xbe, 0x8a, 0xce, 0xb8, 0x14, 0xba, 0xc9, 0x7d, 0xec, 0xcf, 0xd1, 0x3d,
+	0x2e, 0x05, 0xfa, 0x08, 0xd6, 0x28, 0x91, 0x64, 0xc2, 0x12, 0xc9, 0x32, 0x63, 0xe7, 0x69, 0xbb,
+	0xce, 0x02, 0xd6, 0x86, 0xef, 0x43, 0xeb, 0x3a, 0xcd, 0xde, 0x94, 0x46, 0x75, 0x6d, 0x04, 0x06,
+	0xd2, 0x06, 0x1f, 0x80, 0x2f, 0xd4, 0xa1, 0x93, 0x09, 0x33, 0x26, 0x0d, 0x6d, 0xd2, 0x2e, 0xc1,
+	0xd2, 0xc8, 0x4a, 0xc7, 0x69, 0xd0, 0xd4, 0x85, 0xb7, 0x17, 0xe0, 0x98, 0xa2, 0x67, 0xd0, 0x2c,
+	0x52, 0x71, 0x1a, 0x80, 0x36, 0x68, 0x18, 0x60, 0x4c, 0x15, 0x57, 0xf2, 0x9a, 0x5d, 0xa5, 0x93,
+	0xcb, 0xa0, 0xa5, 0x55, 0xa5, 0x88, 0x5e, 0x40, 0x9b, 0xcd, 0x2e, 0x49, 0x2e, 0x64, 0xa4, 0xce,
+	0x18, 0xb4, 0xb5, 0xba, 0x55, 0x60, 0x3f, 0xf0, 0x98, 0xa9, 0x43, 0x08, 0x96, 0xbd, 0x53, 0xbc,
+	0x49, 0x3e, 0x0d, 0x7c, 0xc3, 0x9a, 0x86, 0x4e, 0x15, 0x82, 0xde, 0x03, 0x48, 0xcf, 0xce, 0x73,
+	0x31, 0x21, 0x92, 0xd1, 0xa0, 0xd3, 0x77, 0x06, 0x0d, 0x6c, 0x21, 0xe1, 0x2f, 0x15, 0x68, 0x63,
+	0x92, 0x5c, 0xcc, 0x07, 0xe0, 0x39, 0x80, 0x90, 0x24, 0x2b, 0x52, 0x3a, 0x3a, 0x60, 0x53, 0x23,
+	0x3a, 0xe1, 0x96, 0x1a, 0x03, 0x6a, 0x94, 0x15, 0x53, 0x2e, 0x4b, 0xa8, 0x56, 0xbd, 0x84, 0xff,
+	0x5d, 0x12, 0x11, 0x2d, 0xd3, 0xe1, 0xea, 0x8c, 0x6b, 0x97, 0x44, 0x0c, 0x6d, 0x46, 0xee, 0xd0,
+	0x56, 0xbd, 0x87, 0xb6, 0x10, 0x7c, 0x15, 0x70, 0x41, 0x5d, 0x4d, 0x07, 0x6b, 0x5d, 0x12, 0xf1,
+	0xba, 0x64, 0x6f, 0x89, 0x5a, 0xef, 0x16, 0xb5, 0xf6, 0xcc, 0xd6, 0xff, 0xd1, 0xcc, 0xfe, 0xe6,
+	0x80, 0x5f, 0xd0, 0xb1, 0x52, 0x7b, 0x6f, 0x80, 0x17, 0xf3, 0x24, 0x9a, 0xbf, 0x33, 0xb5, 0x98,
+	0x27, 0x63, 0xaa, 0x61, 0x32, 0x5b, 0x9c, 0xb3, 0x16, 0x93, 0xd9, 0x98, 0xa2, 0xff, 0x03, 0x18,
+	0x6b, 0xeb, 0xcd, 0x69, 0x68, 0x0f, 0xb5, 0x71, 0x94, 0x96, 0xcc, 0x4a, 0xad, 0x57, 0x68, 0x95,
+	0xa3, 0xda, 0x5c, 0x1d, 0x68, 0x9f, 0x48, 0x22, 0x45, 0x39, 0x97, 0x7f, 0x3a, 0xd0, 0xda, 0xd7,
+	0x2b, 0x58, 0xc3, 0xd6, 0x7e, 0x76, 0xec, 0xfd, 0xac, 0xce, 0x93, 0x11, 0x69, 0x0a, 0x77, 0xb0,
+	0xfe, 0x46, 0xeb, 0x50, 0x3b, 0xcb, 0x33, 0x21, 0xcb, 0xa2, 0xb5, 0xa0, 0x7a, 0x8b, 0x12, 0x3e,
+	0xbd, 0x89, 0xde, 0xe6, 0xa9, 0x24, 0xe5, 0x44, 0x6a, 0xe8, 0x7b, 0x85, 0xa8, 0x14, 0x5c, 0x88,
+	0x9c, 0x99, 0x8b, 0x71, 0x71, 0x21, 0xa9, 0xbe, 0x55, 0x61, 0xa3, 0x29, 0x8f, 0xb9, 0xea, 0x3a,
+	0x73, 0x2d, 0x2d, 0x85, 0x1d, 0x18, 0x48, 0x0d, 0xb3, 0x8e, 0x1a, 0xb1, 0xd9, 0x84, 0x31, 0xca,
+	0xcc, 0xeb, 0xe9, 0x62, 0x5f, 0xa3, 0xa3, 0x02, 0x54, 0xcd, 0x68, 0xcc, 0x72, 0xc1, 0xa8, 0x9e,
+	0x3f, 0x17, 0x37, 0x35, 0x72, 0x2a, 0x18, 0x0d, 0xaf, 0xc0, 0x2f, 0x38, 0x58, 0xe9, 0xb2, 0x3e,
+	0x85, 0xba, 0x21, 0xc5, 0xec, 0xbb, 0xd6, 0xce, 0x53, 0xbb, 0x3b, 0x2c, 0x32, 0x71, 0x69, 0xf7,
+	0xf2, 0x00, 0x1a, 0x65, 0xd7, 0xa0, 0x06, 0x54, 0x8f, 0x8e, 0x8f, 0x46, 0xdd, 0x27, 0x08, 0xc0,
+	0xdb, 0xdb, 0x3d, 0x19, 0x7d, 0xb6, 0xd3, 0x75, 0xd0, 0x3a, 0x74, 0xf7, 0xf1, 0xf1, 0xfe, 0xb7,
+	0x5f, 0x1f, 0xe3, 0x61, 0xa4, 0xd0, 0x57, 0x3b, 0xdd, 0x0a, 0xaa, 0x83, 0xfb, 0xcd, 0xe8, 0xc7,
+	0xae, 0x8b, 0x5a, 0x50, 0x1f, 0x8e, 0xf6, 0xc7, 0x87, 0xbb, 0x07, 0xdd, 0xea, 0xce, 0xaf, 0x35,
+	0x80, 0xc3, 0x93, 0x32, 0x23, 0xfa, 0x1c, 0xbc, 0x23, 0x36, 0x93, 0xaa, 0x5f, 0xec, 0x42, 0xe6,
+	0xef, 0x76, 0x6f, 0xf3, 0x36, 0x6c, 0x8e, 0x1d, 0x3e, 0x41, 0x5f, 0x40, 0xdd, 0xb8, 0x8a, 0xc7,
+	0xfb, 0xee, 0x82, 0xa7, 0xcf, 0xc4, 0xd0, 0xd6, 0x9d, 0xe9, 0x28, 0xd7, 0x42, 0xaf, 0x77, 0x9f,
+	0xca, 0x0e, 0x31, 0x64, 0x77, 0x43, 0x0c, 0xd9, 0x83, 0x21, 0x86, 0xec, 0x6e, 0x08, 0xf3, 0x70,
+	0x2d, 0x87, 0x58, 0x7a, 0xef, 0x7a, 0xbd, 0xfb, 0x54, 0x56, 0x88, 0xa6, 0x22, 0xe1, 0x74, 0xca,
+	0x1f, 0xa6, 0xe1, 0x99, 0x0d, 0xdf, 0xfa, 0x39, 0xb3, 0x42, 0xe4, 0xab, 0x87, 0xf8, 0x12, 0xaa,
+	0xea, 0x7d, 0x44, 0x4b, 0xcd, 0x64, 0xbd, 0xa0, 0xbd, 0xe0, 0xae, 0x62, 0xee, 0xfc, 0x15, 0xd4,
+	0xf4, 0xfa, 0x41, 0x4b, 0x46, 0xf6, 0x82, 0xee, 0x6d, 0xdd, 0xa3, 0xb1, 0xfd, 0xcd, 0xf8, 0x2f,
+	0xf9, 0xdb, 0x8b, 0xa2, 0xb7, 0x75, 0x8f, 0xa6, 0xf4, 0x3f, 0xf3, 0xf4, 0x3f, 0xf7, 0xab, 0xbf,
+	0x06, 0x00, 0x2f, 0x89, 0xff, 0x68, 0x8c, 0x0b, 0x00, 0x00,
 }
@@ -36,6 +36,14 @@ var _ server.Option
 type MSnowflakeService interface {
 	NextId(ctx context.Context, in *IdRequest, opts ...client.CallOption) (*IdResponse, error)
 	NextIds(ctx context.Context, in *IdRequest, opts ...client.CallOption) (*IdResponse, error)
+	Encode(ctx context.Context, in *EncodeRequest, opts ...client.CallOption) (*EncodeResponse, error)
+	Decode(ctx context.Context, in *DecodeRequest, opts ...client.CallOption) (*DecodeResponse, error)
+	Reveal(ctx context.Context, in *RevealRequest, opts ...client.CallOption) (*RevealResponse, error)
+	NextUlids(ctx context.Context, in *IdRequest, opts ...client.CallOption) (*BytesIdResponse, error)
+	NextUuids(ctx context.Context, in *IdRequest, opts ...client.CallOption) (*BytesIdResponse, error)
+	Info(ctx context.Context, in *InfoRequest, opts ...client.CallOption) (*InfoResponse, error)
+	Range(ctx context.Context, in *RangeRequest, opts ...client.CallOption) (*RangeResponse, error)
+	Stats(ctx context.Context, in *StatsRequest, opts ...client.CallOption) (*StatsResponse, error)
 }
 
 type mSnowflakeService struct {
@@ -70,17 +78,113 @@ func (c *mSnowflakeService) NextIds(ctx context.Context, in *IdRequest, opts ...
 	return out, nil
 }
 
+func (c *mSnowflakeService) Encode(ctx context.Context, in *EncodeRequest, opts ...client.CallOption) (*EncodeResponse, error) {
+	req := c.c.NewRequest(c.name, "MSnowflake.Encode", in)
+	out := new(EncodeResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mSnowflakeService) Decode(ctx context.Context, in *DecodeRequest, opts ...client.CallOption) (*DecodeResponse, error) {
+	req := c.c.NewRequest(c.name, "MSnowflake.Decode", in)
+	out := new(DecodeResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mSnowflakeService) Reveal(ctx context.Context, in *RevealRequest, opts ...client.CallOption) (*RevealResponse, error) {
+	req := c.c.NewRequest(c.name, "MSnowflake.Reveal", in)
+	out := new(RevealResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mSnowflakeService) NextUlids(ctx context.Context, in *IdRequest, opts ...client.CallOption) (*BytesIdResponse, error) {
+	req := c.c.NewRequest(c.name, "MSnowflake.NextUlids", in)
+	out := new(BytesIdResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mSnowflakeService) NextUuids(ctx context.Context, in *IdRequest, opts ...client.CallOption) (*BytesIdResponse, error) {
+	req := c.c.NewRequest(c.name, "MSnowflake.NextUuids", in)
+	out := new(BytesIdResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mSnowflakeService) Info(ctx context.Context, in *InfoRequest, opts ...client.CallOption) (*InfoResponse, error) {
+	req := c.c.NewRequest(c.name, "MSnowflake.Info", in)
+	out := new(InfoResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mSnowflakeService) Range(ctx context.Context, in *RangeRequest, opts ...client.CallOption) (*RangeResponse, error) {
+	req := c.c.NewRequest(c.name, "MSnowflake.Range", in)
+	out := new(RangeResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mSnowflakeService) Stats(ctx context.Context, in *StatsRequest, opts ...client.CallOption) (*StatsResponse, error) {
+	req := c.c.NewRequest(c.name, "MSnowflake.Stats", in)
+	out := new(StatsResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for MSnowflake service
 
 type MSnowflakeHandler interface {
 	NextId(context.Context, *IdRequest, *IdResponse) error
 	NextIds(context.Context, *IdRequest, *IdResponse) error
+	Encode(context.Context, *EncodeRequest, *EncodeResponse) error
+	Decode(context.Context, *DecodeRequest, *DecodeResponse) error
+	Reveal(context.Context, *RevealRequest, *RevealResponse) error
+	NextUlids(context.Context, *IdRequest, *BytesIdResponse) error
+	NextUuids(context.Context, *IdRequest, *BytesIdResponse) error
+	Info(context.Context, *InfoRequest, *InfoResponse) error
+	Range(context.Context, *RangeRequest, *RangeResponse) error
+	Stats(context.Context, *StatsRequest, *StatsResponse) error
 }
 
 func RegisterMSnowflakeHandler(s server.Server, hdlr MSnowflakeHandler, opts ...server.HandlerOption) error {
 	type mSnowflake interface {
 		NextId(ctx context.Context, in *IdRequest, out *IdResponse) error
 		NextIds(ctx context.Context, in *IdRequest, out *IdResponse) error
+		Encode(ctx context.Context, in *EncodeRequest, out *EncodeResponse) error
+		Decode(ctx context.Context, in *DecodeRequest, out *DecodeResponse) error
+		Reveal(ctx context.Context, in *RevealRequest, out *RevealResponse) error
+		NextUlids(ctx context.Context, in *IdRequest, out *BytesIdResponse) error
+		NextUuids(ctx context.Context, in *IdRequest, out *BytesIdResponse) error
+		Info(ctx context.Context, in *InfoRequest, out *InfoResponse) error
+		Range(ctx context.Context, in *RangeRequest, out *RangeResponse) error
+		Stats(ctx context.Context, in *StatsRequest, out *StatsResponse) error
 	}
 	type MSnowflake struct {
 		mSnowflake
@@ -100,3 +204,35 @@ func (h *mSnowflakeHandler) NextId(ctx context.Context, in *IdRequest, out *IdRe
 func (h *mSnowflakeHandler) NextIds(ctx context.Context, in *IdRequest, out *IdResponse) error {
 	return h.MSnowflakeHandler.NextIds(ctx, in, out)
 }
+
+func (h *mSnowflakeHandler) Encode(ctx context.Context, in *EncodeRequest, out *EncodeResponse) error {
+	return h.MSnowflakeHandler.Encode(ctx, in, out)
+}
+
+func (h *mSnowflakeHandler) Decode(ctx context.Context, in *DecodeRequest, out *DecodeResponse) error {
+	return h.MSnowflakeHandler.Decode(ctx, in, out)
+}
+
+func (h *mSnowflakeHandler) Reveal(ctx context.Context, in *RevealRequest, out *RevealResponse) error {
+	return h.MSnowflakeHandler.Reveal(ctx, in, out)
+}
+
+func (h *mSnowflakeHandler) NextUlids(ctx context.Context, in *IdRequest, out *BytesIdResponse) error {
+	return h.MSnowflakeHandler.NextUlids(ctx, in, out)
+}
+
+func (h *mSnowflakeHandler) NextUuids(ctx context.Context, in *IdRequest, out *BytesIdResponse) error {
+	return h.MSnowflakeHandler.NextUuids(ctx, in, out)
+}
+
+func (h *mSnowflakeHandler) Info(ctx context.Context, in *InfoRequest, out *InfoResponse) error {
+	return h.MSnowflakeHandler.Info(ctx, in, out)
+}
+
+func (h *mSnowflakeHandler) Range(ctx context.Context, in *RangeRequest, out *RangeResponse) error {
+	return h.MSnowflakeHandler.Range(ctx, in, out)
+}
+
+func (h *mSnowflakeHandler) Stats(ctx context.Context, in *StatsRequest, out *StatsResponse) error {
+	return h.MSnowflakeHandler.Stats(ctx, in, out)
+}